@@ -0,0 +1,772 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// AlterAlgorithm is the ALGORITHM clause of ALTER TABLE.
+type AlterAlgorithm string
+
+const (
+	AlterAlgorithmDefault AlterAlgorithm = ""
+	AlterAlgorithmInstant AlterAlgorithm = "INSTANT"
+	AlterAlgorithmInplace AlterAlgorithm = "INPLACE"
+	AlterAlgorithmCopy    AlterAlgorithm = "COPY"
+)
+
+// cost orders algorithms from the cheapest, so that the algorithm of several alterations is the costliest one.
+func (a AlterAlgorithm) cost() int {
+	switch a {
+	case AlterAlgorithmInstant:
+		return 0
+	case AlterAlgorithmInplace:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// AlterLock is the LOCK clause of ALTER TABLE.
+type AlterLock string
+
+const (
+	AlterLockDefault   AlterLock = ""
+	AlterLockNone      AlterLock = "NONE"
+	AlterLockShared    AlterLock = "SHARED"
+	AlterLockExclusive AlterLock = "EXCLUSIVE"
+)
+
+// AlterCopyPolicy is what happens to alterations predicted to copy the table.
+type AlterCopyPolicy int
+
+const (
+	// AlterCopyRefuse refuses them with an AlterCopyError. See AlterOptions.PredictedAlgorithm
+	// for having the server refuse them as well when the prediction is wrong.
+	AlterCopyRefuse AlterCopyPolicy = iota
+	// AlterCopyWarn warns about them and goes ahead.
+	AlterCopyWarn
+	// AlterCopyAllow goes ahead.
+	AlterCopyAllow
+)
+
+type AlterKind int
+
+const (
+	AlterAddColumn AlterKind = iota
+	AlterDropColumn
+	AlterModifyColumn
+	AlterChangePrimaryKey
+	AlterRenameColumn
+	AlterAddIndex
+	AlterDropIndex
+)
+
+func (k AlterKind) String() string {
+	switch k {
+	case AlterAddColumn:
+		return "add column"
+	case AlterDropColumn:
+		return "drop column"
+	case AlterModifyColumn:
+		return "modify column"
+	case AlterChangePrimaryKey:
+		return "change primary key"
+	case AlterRenameColumn:
+		return "rename column"
+	case AlterAddIndex:
+		return "add index"
+	case AlterDropIndex:
+		return "drop index"
+	default:
+		return fmt.Sprintf("<unknown kind: %d>", k)
+	}
+}
+
+// Alteration is one change of a table. Column is the new column, and Previous the column as it was.
+// After is the column an added column follows, and empty for the first column.
+type Alteration struct {
+	Kind       AlterKind
+	Column     *driver.Column
+	Previous   *driver.Column
+	After      string
+	PrimaryKey []string
+	// PreviousPrimaryKey is empty when the table had no primary key.
+	PreviousPrimaryKey []string
+	// Index is an added index, and PreviousIndex a dropped one.
+	Index         *Index
+	PreviousIndex *Index
+	// Change writes a renamed column as CHANGE COLUMN with its whole definition, as servers without
+	// RENAME COLUMN need. It is needed as well when the definition changes.
+	Change bool
+	// Definition is the live definition of Previous. A modified or renamed column keeping its generic
+	// type keeps it, as the generic type would retype the column and lose its default and comment.
+	Definition *ColumnDefinition
+}
+
+// ColumnDefinition is what the server tells of a column beyond its generic type.
+type ColumnDefinition struct {
+	// ColumnType is the full type, like BIGINT UNSIGNED or VARCHAR(64).
+	ColumnType string
+	// Default and OnUpdate are SQL, and empty when the column has none.
+	Default      string
+	OnUpdate     string
+	CharacterSet string
+	Collation    string
+	Comment      string
+	// Generated tells a generated column, whose expression is not read.
+	Generated bool
+}
+
+func (a *Alteration) String() string {
+	switch a.Kind {
+	case AlterAddColumn, AlterModifyColumn:
+		return fmt.Sprintf("%s %s", a.Kind, a.Column.Name)
+	case AlterDropColumn:
+		return fmt.Sprintf("%s %s", a.Kind, a.Previous.Name)
+	case AlterRenameColumn:
+		return fmt.Sprintf("%s %s to %s", a.Kind, a.Previous.Name, a.Column.Name)
+	case AlterAddIndex:
+		return fmt.Sprintf("%s %s", a.Kind, a.Index.Name)
+	case AlterDropIndex:
+		return fmt.Sprintf("%s %s", a.Kind, a.PreviousIndex.Name)
+	default:
+		return fmt.Sprintf("%s (%s) to (%s)", a.Kind, strings.Join(a.PreviousPrimaryKey, ", "), strings.Join(a.PrimaryKey, ", "))
+	}
+}
+
+func (a *Alteration) clause() (string, error) {
+	switch a.Kind {
+	case AlterAddColumn:
+		def, err := generateColumnDefinition(a.Column)
+		if err != nil {
+			return "", err
+		}
+		if a.After == "" {
+			return "ADD COLUMN " + def + " FIRST", nil
+		}
+		return fmt.Sprintf("ADD COLUMN %s AFTER `%s`", def, a.After), nil
+	case AlterDropColumn:
+		return fmt.Sprintf("DROP COLUMN `%s`", a.Previous.Name), nil
+	case AlterModifyColumn:
+		def, err := a.definition()
+		if err != nil {
+			return "", err
+		}
+		return "MODIFY COLUMN " + def, nil
+	case AlterChangePrimaryKey:
+		var parts []string
+		if len(a.PreviousPrimaryKey) > 0 {
+			parts = append(parts, "DROP PRIMARY KEY")
+		}
+		if len(a.PrimaryKey) > 0 {
+			parts = append(parts, fmt.Sprintf("ADD PRIMARY KEY (%s)", quoteColumnNames(a.PrimaryKey)))
+		}
+		return strings.Join(parts, ", "), nil
+	case AlterRenameColumn:
+		if !a.Change && sameDefinition(a.Column, a.Previous) {
+			return fmt.Sprintf("RENAME COLUMN `%s` TO `%s`", a.Previous.Name, a.Column.Name), nil
+		}
+		def, err := a.definition()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("CHANGE COLUMN `%s` %s", a.Previous.Name, def), nil
+	case AlterAddIndex:
+		return "ADD " + generateIndexDefinition(a.Index), nil
+	case AlterDropIndex:
+		return fmt.Sprintf("DROP INDEX `%s`", a.PreviousIndex.Name), nil
+	default:
+		return "", fmt.Errorf("unknown alteration: %s", a.Kind)
+	}
+}
+
+// definition defines the column of a modified or renamed column from its live definition.
+// A column changing its generic type keeps the attributes which still apply to the new type.
+func (a *Alteration) definition() (string, error) {
+	if a.Definition == nil {
+		return "", fmt.Errorf("live definition of %s not read", a.Previous.Name)
+	}
+	if a.Definition.Generated {
+		return "", fmt.Errorf("generated column %s cannot be altered", a.Previous.Name)
+	}
+	if a.Column.Type != a.Previous.Type {
+		return generateRetypedColumnDefinition(a.Column, a.Definition)
+	}
+	return generateLiveColumnDefinition(a.Column, a.Definition), nil
+}
+
+// ServerVersion is the version of the connected server.
+type ServerVersion struct {
+	Major   int
+	Minor   int
+	Patch   int
+	MariaDB bool
+}
+
+var serverVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)\.(\d+)`)
+
+func parseServerVersion(version string) (*ServerVersion, error) {
+	m := serverVersionPattern.FindStringSubmatch(version)
+	if m == nil {
+		return nil, fmt.Errorf("unknown server version: %s", version)
+	}
+	v := &ServerVersion{MariaDB: strings.Contains(strings.ToLower(version), "mariadb")}
+	var err error
+	if v.Major, err = strconv.Atoi(m[1]); err != nil {
+		return nil, err
+	}
+	if v.Minor, err = strconv.Atoi(m[2]); err != nil {
+		return nil, err
+	}
+	if v.Patch, err = strconv.Atoi(m[3]); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// atLeast reports whether v is a MySQL of the given version or later.
+func (v *ServerVersion) atLeast(major, minor, patch int) bool {
+	return !v.MariaDB && v.since(major, minor, patch)
+}
+
+// atLeastMariaDB reports whether v is a MariaDB of the given version or later.
+func (v *ServerVersion) atLeastMariaDB(major, minor, patch int) bool {
+	return v.MariaDB && v.since(major, minor, patch)
+}
+
+func (v *ServerVersion) since(major, minor, patch int) bool {
+	if v.Major != major {
+		return v.Major > major
+	}
+	if v.Minor != minor {
+		return v.Minor > minor
+	}
+	return v.Patch >= patch
+}
+
+// predictAlgorithm predicts the cheapest algorithm the server runs an alteration with,
+// after the online DDL tables of the MySQL manual. last tells an added column goes last.
+func predictAlgorithm(v *ServerVersion, a *Alteration, last bool) AlterAlgorithm {
+	switch a.Kind {
+	case AlterAddColumn:
+		if a.Column.AutoIncrement {
+			return AlterAlgorithmCopy
+		}
+		if v.atLeast(8, 0, 29) || (last && v.atLeast(8, 0, 12)) {
+			return AlterAlgorithmInstant
+		}
+		return AlterAlgorithmInplace
+	case AlterDropColumn:
+		if v.atLeast(8, 0, 29) {
+			return AlterAlgorithmInstant
+		}
+		return AlterAlgorithmInplace
+	case AlterModifyColumn:
+		// only changing whether the column is nullable rebuilds the table in place
+		if a.Column.Type != a.Previous.Type || a.Column.AutoIncrement != a.Previous.AutoIncrement {
+			return AlterAlgorithmCopy
+		}
+		return AlterAlgorithmInplace
+	case AlterChangePrimaryKey:
+		// dropping a primary key without adding another copies the table
+		if len(a.PrimaryKey) == 0 {
+			return AlterAlgorithmCopy
+		}
+		return AlterAlgorithmInplace
+	case AlterRenameColumn:
+		if a.Column.Type != a.Previous.Type || a.Column.AutoIncrement != a.Previous.AutoIncrement {
+			return AlterAlgorithmCopy
+		}
+		if a.Column.NotNull == a.Previous.NotNull && v.atLeast(8, 0, 28) {
+			return AlterAlgorithmInstant
+		}
+		return AlterAlgorithmInplace
+	case AlterAddIndex, AlterDropIndex:
+		// indexes are built in place, and dropping one only changes metadata
+		return AlterAlgorithmInplace
+	default:
+		return AlterAlgorithmCopy
+	}
+}
+
+// hasRenameColumn reports whether the server knows RENAME COLUMN.
+func (v *ServerVersion) hasRenameColumn() bool {
+	return v.atLeast(8, 0, 0) || v.atLeastMariaDB(10, 5, 2)
+}
+
+// AlterPlan is what SetSchema alters in a table and the algorithm the server is predicted to use.
+type AlterPlan struct {
+	TableName   string
+	Alterations []*Alteration
+	// Algorithms are the predicted algorithms of each alteration.
+	Algorithms []AlterAlgorithm
+	Algorithm  AlterAlgorithm
+	// DetectedRenames are the renames found by DetectRenames, from the current name to the new one.
+	// They are applied only once ConfirmRenames confirms them.
+	DetectedRenames map[string]string
+}
+
+// losesData reports whether an alteration of the plan drops or may truncate values.
+func (p *AlterPlan) losesData() bool {
+	for i, a := range p.Alterations {
+		if classifyAlteration(a, p.Algorithms[i]) == ChangeDataLosing {
+			return true
+		}
+	}
+	return false
+}
+
+// diffSchema lists the alterations turning current into sc. Columns are matched by name, or renamed
+// from the current column renames maps them from, and existing columns keep their position.
+func diffSchema(current, sc *driver.Schema, renames map[string]string) []*Alteration {
+	renamedFrom := make(map[string]string, len(renames))
+	for from, to := range renames {
+		renamedFrom[to] = from
+	}
+
+	var alterations []*Alteration
+	for i, col := range sc.Columns {
+		if from, ok := renamedFrom[col.Name]; ok {
+			prev := findColumn(current, from)
+			alterations = append(alterations, &Alteration{Kind: AlterRenameColumn, Column: col, Previous: prev, Change: !sameDefinition(col, prev)})
+			continue
+		}
+		prev := findColumn(current, col.Name)
+		if prev == nil {
+			after := ""
+			if i > 0 {
+				after = sc.Columns[i-1].Name
+			}
+			alterations = append(alterations, &Alteration{Kind: AlterAddColumn, Column: col, After: after})
+			continue
+		}
+		if !sameDefinition(col, prev) {
+			alterations = append(alterations, &Alteration{Kind: AlterModifyColumn, Column: col, Previous: prev})
+		}
+	}
+	for _, col := range current.Columns {
+		if _, ok := renames[col.Name]; !ok && findColumn(sc, col.Name) == nil {
+			alterations = append(alterations, &Alteration{Kind: AlterDropColumn, Previous: col})
+		}
+	}
+
+	pk, prevPK := primaryKeyNames(sc), primaryKeyNames(current)
+	if !sameNames(pk, prevPK) {
+		alterations = append(alterations, &Alteration{Kind: AlterChangePrimaryKey, PrimaryKey: pk, PreviousPrimaryKey: prevPK})
+	}
+	return alterations
+}
+
+func sameDefinition(col, other *driver.Column) bool {
+	return col.Type == other.Type && col.NotNull == other.NotNull && col.AutoIncrement == other.AutoIncrement
+}
+
+// detectRenames pairs a dropped column with an added one at the same position, when the live type and
+// nullability of the dropped column are the ones the added column would be created with.
+func detectRenames(current, sc *driver.Schema, renames map[string]string, defs map[string]*ColumnDefinition) map[string]string {
+	detected := make(map[string]string)
+	renamedTo := make(map[string]bool, len(renames))
+	for _, to := range renames {
+		renamedTo[to] = true
+	}
+	for i, col := range sc.Columns {
+		if i >= len(current.Columns) || renamedTo[col.Name] || findColumn(current, col.Name) != nil {
+			continue
+		}
+		prev := current.Columns[i]
+		if _, ok := renames[prev.Name]; ok || findColumn(sc, prev.Name) != nil {
+			continue
+		}
+		def := defs[prev.Name]
+		if def == nil || def.Generated || prev.Type != col.Type || prev.NotNull != col.NotNull {
+			continue
+		}
+		if typ, err := columnTypeFromGenericToMySQL(col.Type); err != nil || !strings.EqualFold(def.ColumnType, typ) {
+			continue
+		}
+		detected[prev.Name] = col.Name
+	}
+	return detected
+}
+
+func findColumn(sc *driver.Schema, name string) *driver.Column {
+	for _, col := range sc.Columns {
+		if col.Name == name {
+			return col
+		}
+	}
+	return nil
+}
+
+func primaryKeyNames(sc *driver.Schema) []string {
+	if sc.PrimaryKey == nil {
+		return nil
+	}
+	return sc.PrimaryKey.ColumnNames
+}
+
+func newAlterPlan(v *ServerVersion, tableName string, current, sc *driver.Schema, alterations []*Alteration) *AlterPlan {
+	plan := &AlterPlan{TableName: tableName, Alterations: alterations, Algorithm: AlterAlgorithmInstant}
+	for _, a := range alterations {
+		if a.Kind == AlterRenameColumn && !v.hasRenameColumn() {
+			a.Change = true
+		}
+		last := a.Kind == AlterAddColumn && len(sc.Columns) > 0 && sc.Columns[len(sc.Columns)-1] == a.Column
+		algorithm := predictAlgorithm(v, a, last)
+		plan.Algorithms = append(plan.Algorithms, algorithm)
+		if algorithm.cost() > plan.Algorithm.cost() {
+			plan.Algorithm = algorithm
+		}
+	}
+	return plan
+}
+
+// PlanAlter compares a table to sc and predicts how the server would alter it.
+// Columns are renamed as opts tell.
+func (c *Conn) PlanAlter(ctx context.Context, tableName string, sc *driver.Schema, opts *AlterOptions) (*AlterPlan, error) {
+	_, plan, err := c.planAlter(ctx, tableName, sc, opts)
+	return plan, err
+}
+
+// liveTable is what the server tells of a table beyond its generic schema.
+type liveTable struct {
+	schema  *driver.Schema
+	defs    map[string]*ColumnDefinition
+	indexes []*Index
+}
+
+func (c *Conn) getLiveTable(ctx context.Context, v *ServerVersion, tableName string) (*liveTable, error) {
+	sc, err := getSchemaDB(ctx, c.db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defs, err := getColumnDefinitions(ctx, c.db, v, tableName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := getIndexes(ctx, c.db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return &liveTable{schema: sc, defs: defs, indexes: indexes}, nil
+}
+
+// planAlter is PlanAlter returning the live table as well.
+func (c *Conn) planAlter(ctx context.Context, tableName string, sc *driver.Schema, opts *AlterOptions) (*liveTable, *AlterPlan, error) {
+	v, err := c.serverVersion(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	live, err := c.getLiveTable(ctx, v, tableName)
+	if err != nil {
+		return nil, nil, err
+	}
+	current := live.schema
+	renames, detected, err := opts.renames(tableName, current, sc, live.defs)
+	if err != nil {
+		return nil, nil, err
+	}
+	alterations := diffSchema(current, sc, renames)
+	setColumnDefinitions(alterations, live.defs)
+	if desired, ok := opts.indexes(tableName); ok {
+		indexAlterations, err := diffIndexes(tableName, live.indexes, desired, sc, renames)
+		if err != nil {
+			return nil, nil, err
+		}
+		alterations = append(alterations, indexAlterations...)
+	}
+	plan := newAlterPlan(v, tableName, current, sc, alterations)
+	plan.DetectedRenames = detected
+	return live, plan, nil
+}
+
+// setColumnDefinitions gives modified and renamed columns their live definitions.
+func setColumnDefinitions(alterations []*Alteration, defs map[string]*ColumnDefinition) {
+	for _, a := range alterations {
+		if a.Kind == AlterModifyColumn || a.Kind == AlterRenameColumn {
+			a.Definition = defs[a.Previous.Name]
+		}
+	}
+}
+
+// getColumnDefinitions reads the live definitions of the columns of a table by name.
+func getColumnDefinitions(ctx context.Context, db queryer, v *ServerVersion, tableName string) (map[string]*ColumnDefinition, error) {
+	rows, err := getColumnDefinitionsDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	defs := make(map[string]*ColumnDefinition)
+	for rows.Next() {
+		var name string
+		var columnType string
+		var columnDefault sql.NullString
+		var characterSet sql.NullString
+		var collation sql.NullString
+		var comment string
+		var extra string
+		if err := rows.Scan(&name, &columnType, &columnDefault, &characterSet, &collation, &comment, &extra); err != nil {
+			return nil, err
+		}
+		def := &ColumnDefinition{
+			ColumnType:   columnType,
+			Default:      columnDefaultSQL(v, columnType, columnDefault, extra),
+			CharacterSet: characterSet.String,
+			Collation:    collation.String,
+			Comment:      comment,
+			Generated:    generatedColumnPattern.MatchString(extra),
+		}
+		if m := onUpdatePattern.FindStringSubmatch(extra); m != nil {
+			def.OnUpdate = m[1]
+		}
+		defs[name] = def
+	}
+	return defs, rows.Err()
+}
+
+var (
+	generatedColumnPattern = regexp.MustCompile(`(?i)\b(VIRTUAL|STORED|PERSISTENT) GENERATED\b`)
+	onUpdatePattern        = regexp.MustCompile(`(?i)\bon update (\S+)`)
+)
+
+// columnDefaultSQL writes the COLUMN_DEFAULT of INFORMATION_SCHEMA as SQL. MariaDB tells it as SQL
+// already, while MySQL tells literals bare and marks expressions in EXTRA.
+func columnDefaultSQL(v *ServerVersion, columnType string, columnDefault sql.NullString, extra string) string {
+	if !columnDefault.Valid {
+		return ""
+	}
+	d := columnDefault.String
+	if v.atLeastMariaDB(10, 2, 7) {
+		if d == "NULL" {
+			return ""
+		}
+		return d
+	}
+	switch {
+	case strings.HasPrefix(strings.ToUpper(d), "CURRENT_TIMESTAMP"):
+		return d
+	case strings.Contains(strings.ToUpper(extra), "DEFAULT_GENERATED"):
+		return "(" + d + ")"
+	case strings.HasPrefix(strings.ToLower(columnType), "bit") && strings.HasPrefix(d, "b'"):
+		return d
+	default:
+		return quoteString(d)
+	}
+}
+
+func (c *Conn) serverVersion(ctx context.Context) (*ServerVersion, error) {
+	version, err := getServerVersionDB(ctx, c.db)
+	if err != nil {
+		return nil, err
+	}
+	return parseServerVersion(version)
+}
+
+// AlterOptions control the ALTER TABLE statements of SchemaChangeAlter.
+type AlterOptions struct {
+	// Algorithm and Lock are put in the statement. LOCK is left out with ALGORITHM=INSTANT,
+	// which takes no other lock than the default one.
+	Algorithm AlterAlgorithm
+	Lock      AlterLock
+	// PredictedAlgorithm puts the predicted algorithm in the statement without Algorithm, so that
+	// the server refuses the alteration when the prediction is wrong rather than copying the table.
+	PredictedAlgorithm bool
+	Copy               AlterCopyPolicy
+	// Warnf reports alterations copying the table under AlterCopyWarn. It defaults to log.Printf.
+	Warnf func(format string, args ...interface{})
+	// Renames map tables to the columns renamed in them, from the current name to the new one.
+	// Without them, a column missing from the new schema is dropped and a new one added.
+	Renames map[string]map[string]string
+	// DetectRenames renames a dropped column to an added one at the same position, when the live
+	// column has the type and nullability the added one would be created with.
+	DetectRenames bool
+	// ConfirmRenames is asked before detected renames are applied. Without it they are refused.
+	ConfirmRenames ConfirmRenamesFunc
+	// Indexes map tables to all their secondary indexes, which are added and dropped to match.
+	// A table missing from them keeps its indexes.
+	Indexes map[string][]*Index
+}
+
+// indexes returns the desired secondary indexes of a table, if they are given.
+func (opts *AlterOptions) indexes(tableName string) ([]*Index, bool) {
+	if opts == nil {
+		return nil, false
+	}
+	indexes, ok := opts.Indexes[tableName]
+	return indexes, ok
+}
+
+// ConfirmRenamesFunc asks for the confirmation of the renames detected in a table, from the current
+// column name to the new one. The renames are applied only when it returns true.
+type ConfirmRenamesFunc func(ctx context.Context, tableName string, renames map[string]string) (bool, error)
+
+// RenameNotConfirmedError refuses detected renames which ConfirmRenames did not confirm.
+type RenameNotConfirmedError struct {
+	TableName string
+	Renames   map[string]string
+}
+
+func (e *RenameNotConfirmedError) Error() string {
+	var pairs []string
+	for from, to := range e.Renames {
+		pairs = append(pairs, from+" to "+to)
+	}
+	sort.Strings(pairs)
+	return fmt.Sprintf("detected renames of %s are not confirmed: %s", e.TableName, strings.Join(pairs, ", "))
+}
+
+// confirmRenames asks ConfirmRenames for the renames detected in plan.
+func (opts *AlterOptions) confirmRenames(ctx context.Context, plan *AlterPlan) error {
+	if len(plan.DetectedRenames) == 0 {
+		return nil
+	}
+	if opts == nil || opts.ConfirmRenames == nil {
+		return &RenameNotConfirmedError{TableName: plan.TableName, Renames: plan.DetectedRenames}
+	}
+	ok, err := opts.ConfirmRenames(ctx, plan.TableName, plan.DetectedRenames)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return &RenameNotConfirmedError{TableName: plan.TableName, Renames: plan.DetectedRenames}
+	}
+	return nil
+}
+
+// renames checks the renames of a table and adds the detected ones, which it returns as well.
+func (opts *AlterOptions) renames(tableName string, current, sc *driver.Schema, defs map[string]*ColumnDefinition) (map[string]string, map[string]string, error) {
+	if opts == nil {
+		return nil, nil, nil
+	}
+	renames := make(map[string]string)
+	renamedFrom := make(map[string]string)
+	for from, to := range opts.Renames[tableName] {
+		if findColumn(current, from) == nil {
+			return nil, nil, fmt.Errorf("renamed column %s not found in %s", from, tableName)
+		}
+		if findColumn(sc, from) != nil {
+			return nil, nil, fmt.Errorf("renamed column %s is still in the new schema of %s", from, tableName)
+		}
+		if findColumn(sc, to) == nil || findColumn(current, to) != nil {
+			return nil, nil, fmt.Errorf("column %s of %s cannot be renamed to %s", from, tableName, to)
+		}
+		if other, ok := renamedFrom[to]; ok {
+			return nil, nil, fmt.Errorf("columns %s and %s of %s are both renamed to %s", other, from, tableName, to)
+		}
+		renamedFrom[to] = from
+		renames[from] = to
+	}
+	var detected map[string]string
+	if opts.DetectRenames {
+		detected = detectRenames(current, sc, renames, defs)
+		for from, to := range detected {
+			renames[from] = to
+		}
+	}
+	return renames, detected, nil
+}
+
+// AlterCopyError refuses alterations predicted to copy the table.
+type AlterCopyError struct {
+	TableName   string
+	Alterations []*Alteration
+}
+
+func (e *AlterCopyError) Error() string {
+	names := make([]string, len(e.Alterations))
+	for i, a := range e.Alterations {
+		names[i] = a.String()
+	}
+	return fmt.Sprintf("alteration of %s would copy the table: %s", e.TableName, strings.Join(names, ", "))
+}
+
+// statementOptions decides the ALGORITHM and LOCK clauses of plan, or refuses it.
+func (opts *AlterOptions) statementOptions(plan *AlterPlan) (AlterAlgorithm, AlterLock, error) {
+	if opts == nil {
+		opts = &AlterOptions{}
+	}
+	algorithm := opts.Algorithm
+	if plan.Algorithm == AlterAlgorithmCopy && algorithm != AlterAlgorithmCopy {
+		var copying []*Alteration
+		for i, a := range plan.Alterations {
+			if plan.Algorithms[i] == AlterAlgorithmCopy {
+				copying = append(copying, a)
+			}
+		}
+		copyErr := &AlterCopyError{TableName: plan.TableName, Alterations: copying}
+		switch opts.Copy {
+		case AlterCopyAllow:
+		case AlterCopyWarn:
+			warnf := opts.Warnf
+			if warnf == nil {
+				warnf = log.Printf
+			}
+			warnf("%v", copyErr)
+		default:
+			return "", "", copyErr
+		}
+	}
+	if algorithm == AlterAlgorithmDefault && opts.PredictedAlgorithm {
+		algorithm = plan.Algorithm
+	}
+	lock := opts.Lock
+	if algorithm == AlterAlgorithmInstant {
+		lock = AlterLockDefault
+	}
+	return algorithm, lock, nil
+}
+
+// AlterTable alters a table to sc in one ALTER TABLE statement, keeping its rows.
+// Dropping or truncating columns asks ConfirmDrop for the table, and detected renames ConfirmRenames.
+func (c *Conn) AlterTable(ctx context.Context, tableName string, sc *driver.Schema, opts *AlterOptions) error {
+	if err := c.checkWrite("AlterTable"); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		plan, err := c.PlanAlter(ctx, tableName, sc, opts)
+		if err != nil {
+			return err
+		}
+		if plan.losesData() {
+			if err := c.checkDrop(ctx, "AlterTable", tableName); err != nil {
+				return err
+			}
+		}
+		if err := opts.confirmRenames(ctx, plan); err != nil {
+			return err
+		}
+		return c.applyAlterPlan(ctx, plan, opts)
+	})
+}
+
+func (c *Conn) alterTable(ctx context.Context, tableName string, sc *driver.Schema, opts *AlterOptions) error {
+	plan, err := c.PlanAlter(ctx, tableName, sc, opts)
+	if err != nil {
+		return err
+	}
+	if err := opts.confirmRenames(ctx, plan); err != nil {
+		return err
+	}
+	return c.applyAlterPlan(ctx, plan, opts)
+}
+
+func (c *Conn) applyAlterPlan(ctx context.Context, plan *AlterPlan, opts *AlterOptions) error {
+	if len(plan.Alterations) == 0 {
+		return nil
+	}
+	algorithm, lock, err := opts.statementOptions(plan)
+	if err != nil {
+		return err
+	}
+	return alterTableDB(ctx, c.db, plan.TableName, plan.Alterations, algorithm, lock)
+}
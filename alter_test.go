@@ -0,0 +1,261 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DiffSchema(t *testing.T) {
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	current := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("age", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+	sc := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+			driver.NewColumn("email", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+
+	alterations := diffSchema(current, sc, nil)
+	_, err := generateAlterTableQuery("user", alterations, AlterAlgorithmInplace, AlterLockNone)
+	assert.Error(t, err, "modified column without its live definition")
+
+	// the modified column keeps its live type, character set, default and comment
+	setColumnDefinitions(alterations, map[string]*ColumnDefinition{
+		"name": {ColumnType: "varchar(64)", CharacterSet: "utf8mb4", Collation: "utf8mb4_bin", Default: "''", Comment: "user's name"},
+	})
+	q, err := generateAlterTableQuery("user", alterations, AlterAlgorithmInplace, AlterLockNone)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ALTER TABLE `user` MODIFY COLUMN `name` varchar(64) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '' COMMENT 'user''s name', ADD COLUMN `email` TEXT AFTER `name`, DROP COLUMN `age`, ALGORITHM=INPLACE, LOCK=NONE", q)
+	}
+
+	mysql57 := &ServerVersion{Major: 5, Minor: 7, Patch: 30}
+	plan := newAlterPlan(mysql57, "user", current, sc, alterations)
+	assert.Equal(t, []AlterAlgorithm{AlterAlgorithmInplace, AlterAlgorithmInplace, AlterAlgorithmInplace}, plan.Algorithms)
+	assert.Equal(t, AlterAlgorithmInplace, plan.Algorithm)
+
+	mysql8 := &ServerVersion{Major: 8, Minor: 0, Patch: 30}
+	plan = newAlterPlan(mysql8, "user", current, sc, alterations)
+	assert.Equal(t, []AlterAlgorithm{AlterAlgorithmInplace, AlterAlgorithmInstant, AlterAlgorithmInstant}, plan.Algorithms)
+}
+
+func Test_PredictAlgorithm(t *testing.T) {
+	v, err := parseServerVersion("8.0.21-log")
+	if !assert.NoError(t, err) {
+		return
+	}
+	add := &Alteration{Kind: AlterAddColumn, Column: driver.NewColumn("email", 2, driver.ColumnTypeString, false, false)}
+	assert.Equal(t, AlterAlgorithmInstant, predictAlgorithm(v, add, true))
+	assert.Equal(t, AlterAlgorithmInplace, predictAlgorithm(v, add, false))
+
+	mariadb, err := parseServerVersion("10.4.12-MariaDB")
+	if assert.NoError(t, err) {
+		assert.Equal(t, AlterAlgorithmInplace, predictAlgorithm(mariadb, add, true))
+	}
+
+	modify := &Alteration{
+		Kind:     AlterModifyColumn,
+		Column:   driver.NewColumn("age", 2, driver.ColumnTypeInt, false, false),
+		Previous: driver.NewColumn("age", 2, driver.ColumnTypeString, false, false),
+	}
+	assert.Equal(t, AlterAlgorithmCopy, predictAlgorithm(v, modify, false))
+}
+
+func Test_AlterStatementOptions(t *testing.T) {
+	modify := &Alteration{
+		Kind:     AlterModifyColumn,
+		Column:   driver.NewColumn("age", 2, driver.ColumnTypeInt, false, false),
+		Previous: driver.NewColumn("age", 2, driver.ColumnTypeString, false, false),
+	}
+	plan := &AlterPlan{
+		TableName:   "user",
+		Alterations: []*Alteration{modify},
+		Algorithms:  []AlterAlgorithm{AlterAlgorithmCopy},
+		Algorithm:   AlterAlgorithmCopy,
+	}
+
+	_, _, err := (*AlterOptions)(nil).statementOptions(plan)
+	assert.Equal(t, &AlterCopyError{TableName: "user", Alterations: []*Alteration{modify}}, err)
+
+	var warnings int
+	algorithm, _, err := (&AlterOptions{Copy: AlterCopyWarn, Warnf: func(string, ...interface{}) { warnings++ }}).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterAlgorithmDefault, algorithm)
+	assert.Equal(t, 1, warnings)
+
+	algorithm, lock, err := (&AlterOptions{Algorithm: AlterAlgorithmCopy, Lock: AlterLockShared}).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterAlgorithmCopy, algorithm)
+	assert.Equal(t, AlterLockShared, lock)
+
+	plan.Algorithms[0] = AlterAlgorithmInplace
+	plan.Algorithm = AlterAlgorithmInplace
+	algorithm, _, err = (*AlterOptions)(nil).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterAlgorithmDefault, algorithm, "the server chooses unless asked otherwise")
+	algorithm, _, err = (&AlterOptions{PredictedAlgorithm: true}).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterAlgorithmInplace, algorithm)
+
+	// INSTANT takes no LOCK clause
+	plan.Algorithms[0] = AlterAlgorithmInstant
+	plan.Algorithm = AlterAlgorithmInstant
+	algorithm, lock, err = (&AlterOptions{PredictedAlgorithm: true, Lock: AlterLockNone}).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterAlgorithmInstant, algorithm)
+	assert.Equal(t, AlterLockDefault, lock)
+	_, lock, err = (&AlterOptions{Lock: AlterLockNone}).statementOptions(plan)
+	assert.NoError(t, err)
+	assert.Equal(t, AlterLockNone, lock)
+}
+
+func Test_DiffSchemaRenames(t *testing.T) {
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	current := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("age", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+	sc := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("full_name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("years", 2, driver.ColumnTypeInt, false, false),
+		},
+	}
+
+	defs := map[string]*ColumnDefinition{
+		"name": {ColumnType: "text"},
+		"age":  {ColumnType: "varchar(8)"},
+	}
+	renames, detected, err := (&AlterOptions{DetectRenames: true}).renames("user", current, sc, defs)
+	if assert.NoError(t, err) {
+		// age changes its type, so it is not taken for years
+		assert.Equal(t, map[string]string{"name": "full_name"}, renames)
+		assert.Equal(t, map[string]string{"name": "full_name"}, detected)
+	}
+	// full_name would be created as TEXT, which a VARCHAR column is not
+	renames, _, err = (&AlterOptions{DetectRenames: true}).renames("user", current, sc, map[string]*ColumnDefinition{"name": {ColumnType: "varchar(64)"}})
+	if assert.NoError(t, err) {
+		assert.Empty(t, renames)
+	}
+	// nor is a nullable column taken for a NOT NULL one
+	notNull := *sc
+	notNull.Columns = []*driver.Column{sc.Columns[0], driver.NewColumn("full_name", 1, driver.ColumnTypeString, true, false), sc.Columns[2]}
+	renames, _, err = (&AlterOptions{DetectRenames: true}).renames("user", current, &notNull, defs)
+	if assert.NoError(t, err) {
+		assert.Empty(t, renames)
+	}
+	_, _, err = (&AlterOptions{Renames: map[string]map[string]string{"user": {"nickname": "full_name"}}}).renames("user", current, sc, defs)
+	assert.Error(t, err)
+	_, _, err = (&AlterOptions{Renames: map[string]map[string]string{"user": {"id": "full_name"}}}).renames("user", current, sc, defs)
+	assert.Error(t, err, "renamed column still in the new schema")
+	_, _, err = (&AlterOptions{Renames: map[string]map[string]string{"user": {"name": "full_name", "age": "full_name"}}}).renames("user", current, sc, defs)
+	assert.Error(t, err, "two columns renamed to the same one")
+
+	renames, detected, err = (&AlterOptions{
+		Renames:       map[string]map[string]string{"user": {"age": "years"}},
+		DetectRenames: true,
+	}).renames("user", current, sc, defs)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, map[string]string{"name": "full_name", "age": "years"}, renames)
+	assert.Equal(t, map[string]string{"name": "full_name"}, detected)
+
+	// detected renames are applied only once confirmed
+	plan := &AlterPlan{TableName: "user", DetectedRenames: detected}
+	assert.IsType(t, &RenameNotConfirmedError{}, (&AlterOptions{}).confirmRenames(context.Background(), plan))
+	confirm := func(answer bool) ConfirmRenamesFunc {
+		return func(ctx context.Context, tableName string, renames map[string]string) (bool, error) {
+			return answer, nil
+		}
+	}
+	assert.IsType(t, &RenameNotConfirmedError{}, (&AlterOptions{ConfirmRenames: confirm(false)}).confirmRenames(context.Background(), plan))
+	assert.NoError(t, (&AlterOptions{ConfirmRenames: confirm(true)}).confirmRenames(context.Background(), plan))
+
+	defs = map[string]*ColumnDefinition{
+		"name": {ColumnType: "varchar(64)"},
+		"age":  {ColumnType: "varchar(8)"},
+	}
+	mysql8 := &ServerVersion{Major: 8, Minor: 0, Patch: 30}
+	alterations := diffSchema(current, sc, renames)
+	setColumnDefinitions(alterations, defs)
+	plan = newAlterPlan(mysql8, "user", current, sc, alterations)
+	assert.Equal(t, []AlterAlgorithm{AlterAlgorithmInstant, AlterAlgorithmCopy}, plan.Algorithms)
+	q, err := generateAlterTableQuery("user", plan.Alterations, AlterAlgorithmDefault, AlterLockDefault)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ALTER TABLE `user` RENAME COLUMN `name` TO `full_name`, CHANGE COLUMN `age` `years` INT NULL", q)
+	}
+
+	mysql57 := &ServerVersion{Major: 5, Minor: 7, Patch: 30}
+	alterations = diffSchema(current, sc, renames)
+	setColumnDefinitions(alterations, defs)
+	plan = newAlterPlan(mysql57, "user", current, sc, alterations)
+	assert.Equal(t, []AlterAlgorithm{AlterAlgorithmInplace, AlterAlgorithmCopy}, plan.Algorithms)
+	q, err = generateAlterTableQuery("user", plan.Alterations, AlterAlgorithmDefault, AlterLockDefault)
+	if assert.NoError(t, err) {
+		// a pure rename keeps the live type of the column
+		assert.Equal(t, "ALTER TABLE `user` CHANGE COLUMN `name` `full_name` varchar(64) NULL, CHANGE COLUMN `age` `years` INT NULL", q)
+	}
+}
+
+func Test_ColumnDefaultSQL(t *testing.T) {
+	mysql8 := &ServerVersion{Major: 8, Minor: 0, Patch: 30}
+	mariadb := &ServerVersion{Major: 10, Minor: 4, Patch: 12, MariaDB: true}
+	cases := []struct {
+		v          *ServerVersion
+		columnType string
+		def        sql.NullString
+		extra      string
+		expected   string
+	}{
+		{mysql8, "varchar(8)", sql.NullString{}, "", ""},
+		{mysql8, "varchar(8)", sql.NullString{String: "it's", Valid: true}, "", "'it''s'"},
+		{mysql8, "int", sql.NullString{String: "0", Valid: true}, "", "'0'"},
+		{mysql8, "datetime(3)", sql.NullString{String: "CURRENT_TIMESTAMP(3)", Valid: true}, "DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)", "CURRENT_TIMESTAMP(3)"},
+		{mysql8, "double", sql.NullString{String: "rand()", Valid: true}, "DEFAULT_GENERATED", "(rand())"},
+		{mysql8, "bit(1)", sql.NullString{String: "b'1'", Valid: true}, "", "b'1'"},
+		{mariadb, "varchar(8)", sql.NullString{String: "NULL", Valid: true}, "", ""},
+		{mariadb, "varchar(8)", sql.NullString{String: "'x'", Valid: true}, "", "'x'"},
+	}
+	for _, c := range cases {
+		assert.Equal(t, c.expected, columnDefaultSQL(c.v, c.columnType, c.def, c.extra))
+	}
+	assert.Equal(t, []string{"on update CURRENT_TIMESTAMP(3)", "CURRENT_TIMESTAMP(3)"}, onUpdatePattern.FindStringSubmatch("DEFAULT_GENERATED on update CURRENT_TIMESTAMP(3)"))
+	assert.True(t, generatedColumnPattern.MatchString("VIRTUAL GENERATED"))
+	assert.False(t, generatedColumnPattern.MatchString("DEFAULT_GENERATED"))
+}
+
+func Test_RetypedColumnDefinition(t *testing.T) {
+	a := &Alteration{
+		Kind:       AlterModifyColumn,
+		Column:     driver.NewColumn("price", 1, driver.ColumnTypeFloat, true, false),
+		Previous:   driver.NewColumn("price", 1, driver.ColumnTypeInt, true, false),
+		Definition: &ColumnDefinition{ColumnType: "bigint(20) unsigned", Default: "'0'", Comment: "in cents"},
+	}
+	def, err := a.definition()
+	if assert.NoError(t, err) {
+		assert.Equal(t, "`price` FLOAT UNSIGNED NOT NULL DEFAULT '0' COMMENT 'in cents'", def)
+	}
+}
@@ -0,0 +1,228 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// BackupMode is how a table is backed up before SetSchema or SetRows drops it.
+type BackupMode string
+
+const (
+	BackupNone BackupMode = ""
+	// BackupCopy copies the table with CREATE TABLE ... LIKE and INSERT ... SELECT.
+	BackupCopy BackupMode = "copy"
+	// BackupRename renames the table aside, which is instant but moves the foreign keys
+	// referencing the table to the backup.
+	BackupRename BackupMode = "rename"
+)
+
+const (
+	backupTimeFormat = "20060102150405"
+	maxTableNameLen  = 64
+)
+
+var backupNamePattern = regexp.MustCompile(`^(.+)_bak_(\d{14})$`)
+
+// Backup is a table backed up at CreatedAt.
+type Backup struct {
+	Name      string
+	TableName string
+	CreatedAt time.Time
+}
+
+func backupName(tableName string, t time.Time) (string, error) {
+	name := tableName + "_bak_" + t.UTC().Format(backupTimeFormat)
+	if len(name) > maxTableNameLen {
+		return "", fmt.Errorf("backup name of table %s is longer than %d characters", tableName, maxTableNameLen)
+	}
+	return name, nil
+}
+
+// parseBackupName returns nil when name is not the name of a backup.
+func parseBackupName(name string) *Backup {
+	m := backupNamePattern.FindStringSubmatch(name)
+	if m == nil {
+		return nil
+	}
+	t, err := time.Parse(backupTimeFormat, m[2])
+	if err != nil {
+		return nil
+	}
+	return &Backup{Name: name, TableName: m[1], CreatedAt: t}
+}
+
+// newBackupName names a new backup of a table, a second later than an existing one of the same second.
+func (c *Conn) newBackupName(ctx context.Context, tableName string) (string, error) {
+	for t := time.Now(); ; t = t.Add(time.Second) {
+		name, err := backupName(tableName, t)
+		if err != nil {
+			return "", err
+		}
+		exists, err := tableExistsDB(ctx, c.db, name)
+		if err != nil {
+			return "", err
+		}
+		if !exists {
+			return name, nil
+		}
+	}
+}
+
+func (c *Conn) backupMode() BackupMode {
+	if c.opts == nil {
+		return BackupNone
+	}
+	return c.opts.Backup
+}
+
+// copyBackupMode backs up by copy whenever mode backs up at all.
+func copyBackupMode(mode BackupMode) BackupMode {
+	if mode == BackupNone {
+		return BackupNone
+	}
+	return BackupCopy
+}
+
+// backupTable backs up a table by mode, and returns the name of the backup.
+// Nothing is backed up when the table does not exist.
+func (c *Conn) backupTable(ctx context.Context, tableName string, mode BackupMode) (string, error) {
+	if mode == BackupNone {
+		return "", nil
+	}
+	exists, err := tableExistsDB(ctx, c.db, tableName)
+	if err != nil || !exists {
+		return "", err
+	}
+	name, err := c.newBackupName(ctx, tableName)
+	if err != nil {
+		return "", err
+	}
+
+	switch mode {
+	case BackupCopy:
+		if err := copyTableDB(ctx, c.db, tableName, name); err != nil {
+			// a partial copy is no backup
+			if derr := dropTableDB(ctx, c.db, name); derr != nil {
+				return "", fmt.Errorf("%v (drop of partial backup: %v)", err, derr)
+			}
+			return "", err
+		}
+	case BackupRename:
+		if err := renameTablesDB(ctx, c.db, [][2]string{{tableName, name}}); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("unknown backup mode: %s", mode)
+	}
+	return name, nil
+}
+
+// ListBackups returns the backups of a table, or of every table when tableName is empty, newest first.
+func (c *Conn) ListBackups(ctx context.Context, tableName string) ([]*Backup, error) {
+	// backups are just written, so they are read from the primary
+	rows, err := getTableNamesDB(ctx, c.db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var backups []*Backup
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		b := parseBackupName(name)
+		if b == nil || (tableName != "" && b.TableName != tableName) {
+			continue
+		}
+		backups = append(backups, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	sortBackups(backups)
+	return backups, nil
+}
+
+func sortBackups(backups []*Backup) {
+	sort.Slice(backups, func(i, j int) bool {
+		if backups[i].TableName != backups[j].TableName {
+			return backups[i].TableName < backups[j].TableName
+		}
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+}
+
+// PruneBackups drops all but the newest keep backups of a table, or of every table when tableName is empty,
+// and returns the dropped ones. ConfirmDrop is asked for each backup before any is dropped.
+func (c *Conn) PruneBackups(ctx context.Context, tableName string, keep int) ([]*Backup, error) {
+	if err := c.checkWrite("PruneBackups"); err != nil {
+		return nil, err
+	}
+	backups, err := c.ListBackups(ctx, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	var prunable []*Backup
+	var names []string
+	kept := make(map[string]int)
+	for _, b := range backups {
+		if kept[b.TableName] < keep {
+			kept[b.TableName]++
+			continue
+		}
+		prunable = append(prunable, b)
+		names = append(names, b.Name)
+	}
+	if err := c.checkDrop(ctx, "PruneBackups", names...); err != nil {
+		return nil, err
+	}
+
+	var pruned []*Backup
+	for _, b := range prunable {
+		if err := dropTableDB(ctx, c.db, b.Name); err != nil {
+			return pruned, err
+		}
+		pruned = append(pruned, b)
+	}
+	return pruned, nil
+}
+
+// RestoreBackup puts a backup back in place of its table. The current table is renamed to a new backup
+// in the same statement, so the restore can be undone by restoring that one.
+func (c *Conn) RestoreBackup(ctx context.Context, name string) error {
+	b := parseBackupName(name)
+	if b == nil {
+		return fmt.Errorf("not a backup: %s", name)
+	}
+	if err := c.checkWrite("RestoreBackup"); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{b.TableName}, func(ctx context.Context) error {
+		return c.restoreBackup(ctx, b)
+	})
+}
+
+func (c *Conn) restoreBackup(ctx context.Context, b *Backup) error {
+	exists, err := tableExistsDB(ctx, c.db, b.TableName)
+	if err != nil {
+		return err
+	}
+
+	var renames [][2]string
+	if exists {
+		current, err := c.newBackupName(ctx, b.TableName)
+		if err != nil {
+			return err
+		}
+		renames = append(renames, [2]string{b.TableName, current})
+	}
+	renames = append(renames, [2]string{b.Name, b.TableName})
+	return renameTablesDB(ctx, c.db, renames)
+}
@@ -0,0 +1,95 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BackupName(t *testing.T) {
+	at := time.Date(2019, 4, 1, 12, 30, 0, 0, time.UTC)
+	name, err := backupName("user", at)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "user_bak_20190401123000", name)
+	}
+	assert.Equal(t, &Backup{Name: name, TableName: "user", CreatedAt: at}, parseBackupName(name))
+	assert.Nil(t, parseBackupName("user"))
+	assert.Nil(t, parseBackupName("user_bak_2019"))
+
+	_, err = backupName(strings.Repeat("x", 50), at)
+	assert.Error(t, err)
+
+	q, err := generateRenameTablesQuery([][2]string{{"user", "user_bak_20190401123000"}, {"user_bak_20190301000000", "user"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "RENAME TABLE `user` TO `user_bak_20190401123000`, `user_bak_20190301000000` TO `user`", q)
+	}
+}
+
+func Test_Backup(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s?backup=copy", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	fakeRow := &driver.Row{
+		Values: map[string]*driver.GenericColumnValue{
+			"id":   driver.NewGenericColumnValue(fakeSchema.Columns[0], 1),
+			"name": driver.NewGenericColumnValue(fakeSchema.Columns[1], "before"),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+	_, err := insertRow(ConnectionTestUser, ConnectionTestPassword, dbName, tableName, fakeRow)
+	assert.NoError(t, err)
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := conn.(*Conn)
+
+	// Backing up before replacing rows
+	assert.NoError(t, c.SetRows(ctx, tableName, nil))
+	backups, err := c.ListBackups(ctx, tableName)
+	if !assert.NoError(t, err) || !assert.Len(t, backups, 1) {
+		return
+	}
+	rows, err := c.GetRows(ctx, backups[0].Name)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 1)
+	}
+
+	// Restoring the backup
+	assert.NoError(t, c.RestoreBackup(ctx, backups[0].Name))
+	rows, err = c.GetRows(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 1)
+	}
+
+	// Pruning all backups
+	pruned, err := c.PruneBackups(ctx, tableName, 0)
+	assert.NoError(t, err)
+	assert.Len(t, pruned, 1)
+}
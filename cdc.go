@@ -0,0 +1,390 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strconv"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/go-tamate/tamate/driver"
+	replmysql "github.com/siddontang/go-mysql/mysql"
+	"github.com/siddontang/go-mysql/replication"
+)
+
+const (
+	ChangeTypeInsert ChangeType = iota
+	ChangeTypeUpdate
+	ChangeTypeDelete
+)
+
+type ChangeType int
+
+func (ct ChangeType) String() string {
+	switch ct {
+	case ChangeTypeInsert:
+		return "insert"
+	case ChangeTypeUpdate:
+		return "update"
+	case ChangeTypeDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("<unknown type: %d>", ct)
+	}
+}
+
+// Change is a row change captured from the binary log. Before is nil for inserts and After is nil for deletes.
+type Change struct {
+	Type      ChangeType
+	TableName string
+	Before    *driver.Row
+	After     *driver.Row
+}
+
+// ChangeSet is the row changes of one committed transaction.
+// A stream started from Position continues with the next transaction.
+type ChangeSet struct {
+	Changes  []*Change
+	Position *BinlogPosition
+}
+
+type ChangeStreamOptions struct {
+	// ServerID identifies the stream as a replica, so it must differ from every server and other stream.
+	ServerID uint32
+	// Start is where the stream begins, by GTID set if it has one and by file and position otherwise.
+	// A nil Start begins at the current position of the server.
+	Start *BinlogPosition
+	// TableNames restricts the stream to some tables of the connected database.
+	TableNames []string
+}
+
+// ChangeStream delivers the row changes of the connected database on C.
+// C is closed when the stream fails or is closed, and Err reports why.
+type ChangeStream struct {
+	C <-chan *ChangeSet
+
+	syncer *replication.BinlogSyncer
+	cancel context.CancelFunc
+	done   chan struct{}
+	err    error
+}
+
+func (s *ChangeStream) Err() error {
+	select {
+	case <-s.done:
+		return s.err
+	default:
+		return nil
+	}
+}
+
+func (s *ChangeStream) Close() error {
+	s.cancel()
+	<-s.done
+	s.syncer.Close()
+	if s.err == context.Canceled {
+		return nil
+	}
+	return s.err
+}
+
+// StreamChanges connects to the server as a replication client and streams row based binary log events.
+// The server has to run with binlog_format=ROW.
+func (c *Conn) StreamChanges(ctx context.Context, opts *ChangeStreamOptions) (*ChangeStream, error) {
+	if opts == nil || opts.ServerID == 0 {
+		return nil, errors.New("server id of change stream is not set")
+	}
+	if c.DSN == "" {
+		// the replication client connects on its own with the address and user of the DSN
+		return nil, errors.New("change stream needs a connection opened from a DSN")
+	}
+	cfg, err := gomysql.ParseDSN(c.DSN)
+	if err != nil {
+		return nil, err
+	}
+	host, portStr, err := net.SplitHostPort(cfg.Addr)
+	if err != nil {
+		return nil, err
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil {
+		return nil, err
+	}
+
+	start := opts.Start
+	if start == nil {
+		start, err = getBinlogPosition(ctx, c.db)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if start.File == "" && start.GTIDSet == "" {
+		return nil, errors.New("binary log is not enabled")
+	}
+
+	password := cfg.Passwd
+	if c.opts != nil && c.opts.PasswordProvider != nil {
+		if password, err = c.opts.PasswordProvider(ctx); err != nil {
+			return nil, err
+		}
+	}
+	tlsCfg := c.tlsConfig
+	if tlsCfg != nil && tlsCfg.ServerName == "" {
+		tlsCfg = tlsCfg.Clone()
+		tlsCfg.ServerName = host
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID:  opts.ServerID,
+		Flavor:    replmysql.MySQLFlavor,
+		Host:      host,
+		Port:      uint16(port),
+		User:      cfg.User,
+		Password:  password,
+		ParseTime: true,
+		TLSConfig: tlsCfg,
+	})
+	var streamer *replication.BinlogStreamer
+	if start.GTIDSet != "" {
+		gset, err := replmysql.ParseMysqlGTIDSet(start.GTIDSet)
+		if err != nil {
+			syncer.Close()
+			return nil, err
+		}
+		streamer, err = syncer.StartSyncGTID(gset)
+		if err != nil {
+			syncer.Close()
+			return nil, err
+		}
+	} else {
+		streamer, err = syncer.StartSync(replmysql.Position{Name: start.File, Pos: start.Position})
+		if err != nil {
+			syncer.Close()
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	ch := make(chan *ChangeSet)
+	s := &ChangeStream{
+		C:      ch,
+		syncer: syncer,
+		cancel: cancel,
+		done:   make(chan struct{}),
+	}
+	cs := &changeStreamer{
+		db:       c.db,
+		dbName:   cfg.DBName,
+		schemas:  make(map[string]*driver.Schema),
+		masks:    c.maskingRules,
+		position: *start,
+	}
+	if len(opts.TableNames) > 0 {
+		cs.tableNames = make(map[string]bool, len(opts.TableNames))
+		for _, n := range opts.TableNames {
+			cs.tableNames[n] = true
+		}
+	}
+
+	go func() {
+		defer close(s.done)
+		defer close(ch)
+		s.err = cs.run(ctx, streamer, ch)
+	}()
+	return s, nil
+}
+
+type changeStreamer struct {
+	db         *sql.DB
+	dbName     string
+	tableNames map[string]bool
+	schemas    map[string]*driver.Schema
+	// masks are the masking rules of the connection, which apply to changes as they do to GetRows
+	masks    MaskingRules
+	position BinlogPosition
+	pending  []*Change
+}
+
+func (cs *changeStreamer) run(ctx context.Context, streamer *replication.BinlogStreamer, ch chan<- *ChangeSet) error {
+	for {
+		ev, err := streamer.GetEvent(ctx)
+		if err != nil {
+			return err
+		}
+		set, err := cs.handle(ctx, ev)
+		if err != nil {
+			return err
+		}
+		if set == nil {
+			continue
+		}
+		select {
+		case ch <- set:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// handle returns the changes of a transaction once its commit is read.
+func (cs *changeStreamer) handle(ctx context.Context, ev *replication.BinlogEvent) (*ChangeSet, error) {
+	if ev.Header.LogPos > 0 {
+		cs.position.Position = ev.Header.LogPos
+	}
+
+	switch e := ev.Event.(type) {
+	case *replication.RotateEvent:
+		cs.position.File = string(e.NextLogName)
+		cs.position.Position = uint32(e.Position)
+	case *replication.RowsEvent:
+		return nil, cs.handleRows(ctx, ev.Header.EventType, e)
+	case *replication.XIDEvent:
+		return cs.commit(e.GSet), nil
+	case *replication.QueryEvent:
+		switch string(e.Query) {
+		case "BEGIN":
+		case "COMMIT":
+			// transactions of non transactional tables end without XID
+			return cs.commit(e.GSet), nil
+		default:
+			// DDL, so the cached schemas may be stale
+			cs.schemas = make(map[string]*driver.Schema)
+			return cs.commit(e.GSet), nil
+		}
+	}
+	return nil, nil
+}
+
+func (cs *changeStreamer) commit(gset replmysql.GTIDSet) *ChangeSet {
+	if gset != nil {
+		cs.position.GTIDSet = gset.String()
+	}
+	if len(cs.pending) == 0 {
+		return nil
+	}
+	pos := cs.position
+	set := &ChangeSet{Changes: cs.pending, Position: &pos}
+	cs.pending = nil
+	return set
+}
+
+func (cs *changeStreamer) handleRows(ctx context.Context, eventType replication.EventType, e *replication.RowsEvent) error {
+	tableName := string(e.Table.Table)
+	if string(e.Table.Schema) != cs.dbName {
+		return nil
+	}
+	if cs.tableNames != nil && !cs.tableNames[tableName] {
+		return nil
+	}
+
+	sc, ok := cs.schemas[tableName]
+	if !ok {
+		var err error
+		sc, err = getSchemaDB(ctx, cs.db, tableName)
+		if err != nil {
+			return err
+		}
+		cs.schemas[tableName] = sc
+	}
+
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		for _, values := range e.Rows {
+			after, err := binlogRow(sc, values, cs.masks[tableName])
+			if err != nil {
+				return err
+			}
+			cs.pending = append(cs.pending, &Change{Type: ChangeTypeInsert, TableName: tableName, After: after})
+		}
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		// rows come in pairs of before and after images
+		for i := 0; i+1 < len(e.Rows); i += 2 {
+			before, err := binlogRow(sc, e.Rows[i], cs.masks[tableName])
+			if err != nil {
+				return err
+			}
+			after, err := binlogRow(sc, e.Rows[i+1], cs.masks[tableName])
+			if err != nil {
+				return err
+			}
+			cs.pending = append(cs.pending, &Change{Type: ChangeTypeUpdate, TableName: tableName, Before: before, After: after})
+		}
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		for _, values := range e.Rows {
+			before, err := binlogRow(sc, values, cs.masks[tableName])
+			if err != nil {
+				return err
+			}
+			cs.pending = append(cs.pending, &Change{Type: ChangeTypeDelete, TableName: tableName, Before: before})
+		}
+	}
+	return nil
+}
+
+// binlogRow converts a row image to the values GetRows would have read, masked the same way.
+func binlogRow(sc *driver.Schema, values []interface{}, masks map[string]MaskFunc) (*driver.Row, error) {
+	if len(values) != len(sc.Columns) {
+		return nil, fmt.Errorf("row image of %s has %d columns, but schema has %d", sc.Name, len(values), len(sc.Columns))
+	}
+
+	rowValues := make(driver.RowValues, len(sc.Columns))
+	rowValuesGroupByKey := make(driver.GroupByKey)
+	for i, col := range sc.Columns {
+		val, err := binlogValue(col, values[i])
+		if err != nil {
+			return nil, err
+		}
+		colValue := &driver.GenericColumnValue{Column: col, Value: val}
+		rowValues[col.Name] = colValue
+		if sc.PrimaryKey == nil {
+			continue
+		}
+		for _, name := range sc.PrimaryKey.ColumnNames {
+			if name == col.Name {
+				key := sc.PrimaryKey.String()
+				rowValuesGroupByKey[key] = append(rowValuesGroupByKey[key], colValue)
+			}
+		}
+	}
+	row := &driver.Row{GroupByKey: rowValuesGroupByKey, Values: rowValues}
+	if err := maskRows(masks, []*driver.Row{row}); err != nil {
+		return nil, err
+	}
+	return row, nil
+}
+
+func binlogValue(col *driver.Column, value interface{}) (interface{}, error) {
+	var scanner sql.Scanner
+	switch col.Type {
+	case driver.ColumnTypeInt:
+		scanner = &sql.NullInt64{}
+	case driver.ColumnTypeFloat:
+		scanner = &sql.NullFloat64{}
+	case driver.ColumnTypeBool:
+		scanner = &sql.NullBool{}
+	case driver.ColumnTypeString:
+		scanner = &sql.NullString{}
+	case driver.ColumnTypeDatetime, driver.ColumnTypeDate:
+		scanner = &gomysql.NullTime{}
+	case driver.ColumnTypeBytes:
+		if s, ok := value.(string); ok {
+			return []byte(s), nil
+		}
+		return value, nil
+	default:
+		return value, nil
+	}
+
+	if err := scanner.Scan(value); err != nil {
+		return nil, fmt.Errorf("column %s: %v", col.Name, err)
+	}
+	v := reflect.ValueOf(scanner).Elem().Interface()
+	if !col.NotNull {
+		return v, nil
+	}
+	// NOT NULL columns are read without the sql.Null* wrapper
+	return reflect.ValueOf(v).Field(0).Interface(), nil
+}
@@ -0,0 +1,161 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/go-tamate/tamate/driver"
+	"github.com/siddontang/go-mysql/replication"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ChangeStreamerHandle(t *testing.T) {
+	ctx := context.Background()
+	sc := &driver.Schema{
+		Name: "example",
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+		},
+	}
+	cs := &changeStreamer{
+		dbName:   "tamatest",
+		schemas:  map[string]*driver.Schema{"example": sc},
+		position: BinlogPosition{File: "mysql-bin.000001", Position: 4},
+	}
+	table := &replication.TableMapEvent{Schema: []byte("tamatest"), Table: []byte("example")}
+	events := []*replication.BinlogEvent{
+		&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.ROTATE_EVENT},
+			Event:  &replication.RotateEvent{NextLogName: []byte("mysql-bin.000002"), Position: 4},
+		},
+		&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.QUERY_EVENT, LogPos: 100},
+			Event:  &replication.QueryEvent{Query: []byte("BEGIN")},
+		},
+		&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 200},
+			Event:  &replication.RowsEvent{Table: table, Rows: [][]interface{}{{int32(1), "user"}}},
+		},
+		&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.UPDATE_ROWS_EVENTv2, LogPos: 300},
+			Event:  &replication.RowsEvent{Table: table, Rows: [][]interface{}{{int32(1), "user"}, {int32(1), nil}}},
+		},
+		&replication.BinlogEvent{
+			Header: &replication.EventHeader{EventType: replication.WRITE_ROWS_EVENTv2, LogPos: 400},
+			Event:  &replication.RowsEvent{Table: &replication.TableMapEvent{Schema: []byte("other"), Table: []byte("example")}, Rows: [][]interface{}{{int32(2), "other"}}},
+		},
+	}
+	for _, ev := range events {
+		set, err := cs.handle(ctx, ev)
+		assert.NoError(t, err)
+		assert.Nil(t, set)
+	}
+
+	set, err := cs.handle(ctx, &replication.BinlogEvent{
+		Header: &replication.EventHeader{EventType: replication.XID_EVENT, LogPos: 500},
+		Event:  &replication.XIDEvent{XID: 1},
+	})
+	if assert.NoError(t, err) && assert.NotNil(t, set) {
+		assert.Equal(t, &BinlogPosition{File: "mysql-bin.000002", Position: 500}, set.Position)
+		if assert.Len(t, set.Changes, 2) {
+			insert := set.Changes[0]
+			assert.Equal(t, ChangeTypeInsert, insert.Type)
+			assert.Nil(t, insert.Before)
+			assert.Equal(t, int64(1), insert.After.Values["id"].Value)
+			assert.Equal(t, sql.NullString{String: "user", Valid: true}, insert.After.Values["name"].Value)
+
+			update := set.Changes[1]
+			assert.Equal(t, ChangeTypeUpdate, update.Type)
+			assert.Equal(t, sql.NullString{String: "user", Valid: true}, update.Before.Values["name"].Value)
+			assert.Equal(t, sql.NullString{}, update.After.Values["name"].Value)
+		}
+	}
+}
+
+func Test_BinlogRow(t *testing.T) {
+	sc := &driver.Schema{
+		Name: "example",
+		Columns: []*driver.Column{
+			driver.NewColumn("name", 0, driver.ColumnTypeString, false, false),
+			driver.NewColumn("created_at", 1, driver.ColumnTypeDatetime, true, false),
+			driver.NewColumn("deleted_at", 2, driver.ColumnTypeDatetime, false, false),
+		},
+	}
+	createdAt := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	row, err := binlogRow(sc, []interface{}{"user", createdAt, nil}, map[string]MaskFunc{"name": MaskNull()})
+	if assert.NoError(t, err) {
+		// values are masked and wrapped as GetRows reads them
+		assert.Equal(t, sql.NullString{}, row.Values["name"].Value)
+		assert.Equal(t, createdAt, row.Values["created_at"].Value)
+		assert.Equal(t, gomysql.NullTime{}, row.Values["deleted_at"].Value)
+	}
+}
+
+func Test_StreamChanges(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	fakeRow := &driver.Row{
+		Values: map[string]*driver.GenericColumnValue{
+			"id":   driver.NewGenericColumnValue(fakeSchema.Columns[0], 1),
+			"name": driver.NewGenericColumnValue(fakeSchema.Columns[1], "user"),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+
+	// Open connection
+	conn, err := newMySQLConn(dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// Streaming changes
+	stream, err := conn.StreamChanges(ctx, &ChangeStreamOptions{ServerID: 1001})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer stream.Close()
+
+	_, err = insertRow(ConnectionTestUser, ConnectionTestPassword, dbName, tableName, fakeRow)
+	assert.NoError(t, err)
+
+	select {
+	case set, ok := <-stream.C:
+		if assert.True(t, ok, "stream closed: %v", stream.Err()) && assert.Len(t, set.Changes, 1) {
+			assert.Equal(t, ChangeTypeInsert, set.Changes[0].Type)
+			assert.Equal(t, int64(1), set.Changes[0].After.Values["id"].Value)
+			assert.Equal(t, "user", set.Changes[0].After.Values["name"].Value)
+			assert.NotEmpty(t, set.Position.GTIDSet)
+		}
+	case <-time.After(10 * time.Second):
+		t.Error("no change streamed")
+	}
+}
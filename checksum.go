@@ -0,0 +1,208 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+const defaultChecksumChunkSize = 1000
+
+// Chunk is a range of rows by primary key, Lower exclusive and Upper inclusive.
+// A nil bound leaves its side of the range open.
+type Chunk struct {
+	Lower []interface{}
+	Upper []interface{}
+}
+
+func (ch *Chunk) args() []interface{} {
+	args := make([]interface{}, 0, len(ch.Lower)+len(ch.Upper))
+	args = append(args, ch.Lower...)
+	return append(args, ch.Upper...)
+}
+
+func (ch *Chunk) where(pk []string) string {
+	return generateChunkCondition(pk, ch.Lower != nil, ch.Upper != nil)
+}
+
+type ChunkChecksum struct {
+	Chunk    *Chunk
+	Count    int64
+	Checksum uint64
+}
+
+// ChunkDiff holds the rows of a chunk whose checksums differ between two tables.
+type ChunkDiff struct {
+	Chunk      *Chunk
+	SourceRows []*driver.Row
+	TargetRows []*driver.Row
+}
+
+type ChecksumOptions struct {
+	// ChunkSize is the number of rows per chunk. It defaults to 1000.
+	ChunkSize int
+}
+
+func (opts *ChecksumOptions) chunkSize() int {
+	if opts == nil || opts.ChunkSize <= 0 {
+		return defaultChecksumChunkSize
+	}
+	return opts.ChunkSize
+}
+
+// ChecksumTable splits a table into chunks by primary key and checksums each of them on the server.
+func (c *Conn) ChecksumTable(ctx context.Context, tableName string, opts *ChecksumOptions) ([]*ChunkChecksum, error) {
+	var checksums []*ChunkChecksum
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		// the chunks and their checksums are read from one connection, so that they agree
+		db := c.reader(ctx)
+		sc, err := getSchemaDB(ctx, db, tableName)
+		if err != nil {
+			return err
+		}
+		checksums, err = checksumTable(ctx, db, sc, opts.chunkSize())
+		return err
+	})
+	return checksums, err
+}
+
+func checksumTable(ctx context.Context, db queryer, sc *driver.Schema, chunkSize int) ([]*ChunkChecksum, error) {
+	chunks, err := chunks(ctx, db, sc, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+
+	checksums := make([]*ChunkChecksum, len(chunks))
+	for i, chunk := range chunks {
+		count, checksum, err := selectChunkChecksumDB(ctx, db, sc, chunk)
+		if err != nil {
+			return nil, err
+		}
+		checksums[i] = &ChunkChecksum{Chunk: chunk, Count: count, Checksum: checksum}
+	}
+	return checksums, nil
+}
+
+// CompareTable checksums the chunks of a table on c and target, and reads the rows of the chunks
+// that differ from both.
+func (c *Conn) CompareTable(ctx context.Context, tableName string, target *Conn, opts *ChecksumOptions) ([]*ChunkDiff, error) {
+	if target == nil {
+		return nil, errors.New("target is nil")
+	}
+	// each side is read from one connection, so that its checksums and rows agree
+	db, tdb := c.reader(ctx), target.reader(ctx)
+	sc, err := getSchemaDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	tsc, err := getSchemaDB(ctx, tdb, tableName)
+	if err != nil {
+		return nil, err
+	}
+	if err := sameColumns(sc, tsc); err != nil {
+		return nil, err
+	}
+
+	checksums, err := checksumTable(ctx, db, sc, opts.chunkSize())
+	if err != nil {
+		return nil, err
+	}
+	var diffs []*ChunkDiff
+	for _, cc := range checksums {
+		count, checksum, err := selectChunkChecksumDB(ctx, tdb, sc, cc.Chunk)
+		if err != nil {
+			return nil, err
+		}
+		if count == cc.Count && checksum == cc.Checksum {
+			continue
+		}
+
+		where := cc.Chunk.where(sc.PrimaryKey.ColumnNames)
+		sourceRows, err := selectSchemaRowsDB(ctx, db, sc, c.maskingRules[tableName], where, cc.Chunk.args()...)
+		if err != nil {
+			return nil, err
+		}
+		targetRows, err := selectSchemaRowsDB(ctx, tdb, tsc, target.maskingRules[tableName], where, cc.Chunk.args()...)
+		if err != nil {
+			return nil, err
+		}
+		diffs = append(diffs, &ChunkDiff{Chunk: cc.Chunk, SourceRows: sourceRows, TargetRows: targetRows})
+	}
+	return diffs, nil
+}
+
+// chunks finds the upper bound of every chunkSize rows. The last chunk has no upper bound,
+// so that rows beyond the last bound are still covered when the chunks are applied to another table.
+func chunks(ctx context.Context, db queryer, sc *driver.Schema, chunkSize int) ([]*Chunk, error) {
+	pkSchema, err := primaryKeySchema(sc)
+	if err != nil {
+		return nil, err
+	}
+
+	var chunks []*Chunk
+	var lower []interface{}
+	for {
+		upper, err := chunkBoundary(ctx, db, pkSchema, lower, chunkSize)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, &Chunk{Lower: lower, Upper: upper})
+		if upper == nil {
+			return chunks, nil
+		}
+		lower = upper
+	}
+}
+
+// primaryKeySchema returns the part of sc which is its primary key, for reading chunk boundaries.
+func primaryKeySchema(sc *driver.Schema) (*driver.Schema, error) {
+	if sc.PrimaryKey == nil || len(sc.PrimaryKey.ColumnNames) == 0 {
+		return nil, errors.New("primary key not found: " + sc.Name)
+	}
+	pkSchema := &driver.Schema{Name: sc.Name, PrimaryKey: sc.PrimaryKey}
+	for _, name := range sc.PrimaryKey.ColumnNames {
+		for _, col := range sc.Columns {
+			if col.Name == name {
+				pkSchema.Columns = append(pkSchema.Columns, col)
+			}
+		}
+	}
+	return pkSchema, nil
+}
+
+// chunkBoundary reads the upper bound of the chunk of chunkSize rows after lower, or nil when fewer rows are left.
+func chunkBoundary(ctx context.Context, db queryer, pkSchema *driver.Schema, lower []interface{}, chunkSize int) ([]interface{}, error) {
+	resultRows, err := selectChunkBoundaryDB(ctx, db, pkSchema, lower, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	defer resultRows.Close()
+	rows, err := scanRows(resultRows, pkSchema, nil)
+	if err != nil || len(rows) == 0 {
+		return nil, err
+	}
+
+	boundary := make([]interface{}, len(pkSchema.Columns))
+	for i, col := range pkSchema.Columns {
+		v, err := sqlValue(rows[0].Values[col.Name].Value)
+		if err != nil {
+			return nil, err
+		}
+		boundary[i] = v
+	}
+	return boundary, nil
+}
+
+func sameColumns(sc, other *driver.Schema) error {
+	if len(sc.Columns) != len(other.Columns) {
+		return fmt.Errorf("columns of %s differ", sc.Name)
+	}
+	for i, col := range sc.Columns {
+		if other.Columns[i].Name != col.Name {
+			return fmt.Errorf("columns of %s differ: %s and %s", sc.Name, col.Name, other.Columns[i].Name)
+		}
+	}
+	return nil
+}
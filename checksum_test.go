@@ -0,0 +1,44 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GenerateChunkQueries(t *testing.T) {
+	sc := &driver.Schema{
+		Name: "example",
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+		},
+	}
+
+	q, err := generateChunkBoundaryQuery(sc, false, 1000)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT `id` FROM `example` ORDER BY `id` LIMIT 1 OFFSET 999", q)
+	}
+	q, err = generateChunkBoundaryQuery(sc, true, 1000)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT `id` FROM `example` WHERE `id` > ? ORDER BY `id` LIMIT 1 OFFSET 999", q)
+	}
+
+	q, err = generateChunkChecksumQuery(sc, true, true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', `id`, `name`, CONCAT(ISNULL(`id`), ISNULL(`name`))))), 0) FROM `example` WHERE `id` > ? AND `id` <= ?", q)
+	}
+	q, err = generateChunkChecksumQuery(sc, false, false)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(CONCAT_WS('#', `id`, `name`, CONCAT(ISNULL(`id`), ISNULL(`name`))))), 0) FROM `example`", q)
+	}
+
+	chunk := &Chunk{Lower: []interface{}{int64(1), "a"}, Upper: []interface{}{int64(5), "b"}}
+	assert.Equal(t, "(`tenant_id`, `id`) > (?, ?) AND (`tenant_id`, `id`) <= (?, ?)", chunk.where([]string{"tenant_id", "id"}))
+	assert.Equal(t, []interface{}{int64(1), "a", int64(5), "b"}, chunk.args())
+}
@@ -2,22 +2,41 @@ package mysql
 
 import (
 	"context"
+	"crypto/tls"
 	"database/sql"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
 
 	"github.com/go-tamate/tamate/driver"
 )
 
-type mysqlConn struct {
-	DSN string
-	db  *sql.DB
+// Conn is a connection to a MySQL database. Besides driver.Conn, it has the methods of this driver
+// beyond the tamate API, like AlterTable, PlanMigration or StreamChanges.
+type Conn struct {
+	DSN  string
+	db   *sql.DB
+	opts *Options
+	// ownsDB is false when the pool belongs to the caller, who closes it
+	ownsDB bool
+	// tlsConfig is the TLS config registered for the TLS files of opts
+	tlsConfig *tls.Config
+	replicas  *replicaSet
+
+	maskingRules MaskingRules
+	snapshot     *snapshot
+}
+
+func newMySQLConn(dsn string) (*Conn, error) {
+	return newMySQLConnWithOptions(dsn, &Options{})
 }
 
-func newMySQLConn(dsn string) (*mysqlConn, error) {
-	mc := &mysqlConn{
-		DSN: dsn,
+func newMySQLConnWithOptions(dsn string, opts *Options) (*Conn, error) {
+	mc := &Conn{
+		DSN:    dsn,
+		opts:   opts,
+		ownsDB: true,
 	}
 	if err := mc.Open(); err != nil {
 		return nil, err
@@ -25,31 +44,103 @@ func newMySQLConn(dsn string) (*mysqlConn, error) {
 	return mc, nil
 }
 
-func (c *mysqlConn) Open() error {
-	db, err := sql.Open("mysql", c.DSN)
+func (c *Conn) Open() error {
+	db, tlsCfg, err := c.opts.openDB(c.DSN)
 	if err != nil {
 		return err
 	}
 	if err := db.Ping(); err != nil {
 		return err
 	}
+	// replicas are not pinged, as reads go to the primary while they are down
+	replicas, err := c.opts.openReplicas()
+	if err != nil {
+		if cerr := db.Close(); cerr != nil {
+			return cerr
+		}
+		return err
+	}
 	c.db = db
+	c.tlsConfig = tlsCfg
+	c.replicas = replicas
 	return nil
 }
 
-func (c *mysqlConn) Close() error {
+// openDB opens a pool of dsn configured by opts.
+func (opts *Options) openDB(dsn string) (*sql.DB, *tls.Config, error) {
+	dsn, tlsCfg, err := opts.configureDSN(dsn)
+	if err != nil {
+		return nil, nil, err
+	}
+	var db *sql.DB
+	if opts.PasswordProvider != nil {
+		db = sql.OpenDB(&passwordConnector{dsn: dsn, provider: opts.PasswordProvider})
+	} else if db, err = sql.Open("mysql", dsn); err != nil {
+		return nil, nil, err
+	}
+	// unset limits keep the defaults of database/sql
+	if opts.MaxOpenConns != 0 {
+		db.SetMaxOpenConns(opts.MaxOpenConns)
+	}
+	if opts.MaxIdleConns != 0 {
+		db.SetMaxIdleConns(opts.MaxIdleConns)
+	}
+	if opts.ConnMaxLifetime != 0 {
+		db.SetConnMaxLifetime(opts.ConnMaxLifetime)
+	}
+	return db, tlsCfg, nil
+}
+
+func (c *Conn) Close() error {
 	if c.db == nil {
 		return errors.New("datastore is not opened")
 	}
-	if err := c.db.Close(); err != nil {
-		return err
+	if c.snapshot != nil {
+		if err := c.EndSnapshot(); err != nil {
+			return err
+		}
+	}
+	if c.replicas != nil {
+		if err := c.replicas.close(); err != nil {
+			return err
+		}
+		c.replicas = nil
+	}
+	if c.ownsDB {
+		if err := c.db.Close(); err != nil {
+			return err
+		}
 	}
 	c.db = nil
 	return nil
 }
 
-func (c *mysqlConn) GetSchema(ctx context.Context, tableName string) (*driver.Schema, error) {
-	rows, err := getInfomationSchemaDB(c.db, tableName)
+// reader returns the connection reads go through: the snapshot while one is active,
+// and otherwise a healthy replica or the primary when there is none.
+func (c *Conn) reader(ctx context.Context) queryer {
+	if c.snapshot != nil {
+		return c.snapshot.conn
+	}
+	if c.replicas != nil {
+		if db := c.replicas.pick(ctx); db != nil {
+			return db
+		}
+	}
+	return c.db
+}
+
+func (c *Conn) GetSchema(ctx context.Context, tableName string) (*driver.Schema, error) {
+	var sc *driver.Schema
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		sc, err = getSchemaDB(ctx, c.reader(ctx), tableName)
+		return err
+	})
+	return sc, err
+}
+
+func getSchemaDB(ctx context.Context, db queryer, tableName string) (*driver.Schema, error) {
+	rows, err := getInfomationSchemaDB(ctx, db, tableName)
 	if err != nil {
 		return nil, err
 	}
@@ -70,30 +161,9 @@ func (c *mysqlConn) GetSchema(ctx context.Context, tableName string) (*driver.Sc
 		if err := rows.Scan(&columnName, &ordinalPosition, &columnType, &columnKey, &isNullable, &extra); err != nil {
 			return nil, err
 		}
-
-		// key
-		if strings.Contains(columnKey, "PRI") {
-			if schema.PrimaryKey == nil {
-				schema.PrimaryKey = &driver.Key{
-					KeyType: driver.KeyTypePrimary,
-				}
-			}
-			schema.PrimaryKey.ColumnNames = append(schema.PrimaryKey.ColumnNames, columnName)
-		}
-
-		// column
-		ct, err := columnTypeFromMySQLToGeneric(columnType)
-		if err != nil {
+		if err := appendColumn(schema, columnName, ordinalPosition, columnType, columnKey, isNullable, extra); err != nil {
 			return nil, err
 		}
-		column := &driver.Column{
-			Name:            columnName,
-			OrdinalPosition: ordinalPosition - 1,
-			Type:            ct,
-			NotNull:         isNullable != "YES",
-			AutoIncrement:   strings.Contains(extra, "auto_increment"),
-		}
-		schema.Columns = append(schema.Columns, column)
 	}
 
 	if schema == nil {
@@ -102,24 +172,117 @@ func (c *mysqlConn) GetSchema(ctx context.Context, tableName string) (*driver.Sc
 	return schema, nil
 }
 
-func (c *mysqlConn) SetSchema(ctx context.Context, tableName string, sc *driver.Schema) error {
-	dropTableDB(c.db, tableName)
-	createTableDB(c.db, sc)
+func appendColumn(schema *driver.Schema, columnName string, ordinalPosition int, columnType, columnKey, isNullable, extra string) error {
+	// key
+	if strings.Contains(columnKey, "PRI") {
+		if schema.PrimaryKey == nil {
+			schema.PrimaryKey = &driver.Key{
+				KeyType: driver.KeyTypePrimary,
+			}
+		}
+		schema.PrimaryKey.ColumnNames = append(schema.PrimaryKey.ColumnNames, columnName)
+	}
+
+	// column
+	ct, err := columnTypeFromMySQLToGeneric(columnType)
+	if err != nil {
+		return err
+	}
+	column := &driver.Column{
+		Name:            columnName,
+		OrdinalPosition: ordinalPosition - 1,
+		Type:            ct,
+		NotNull:         isNullable != "YES",
+		AutoIncrement:   strings.Contains(extra, "auto_increment"),
+	}
+	schema.Columns = append(schema.Columns, column)
 	return nil
 }
 
-func (c *mysqlConn) GetRows(ctx context.Context, tableName string) ([]*driver.Row, error) {
-	schema, err := c.GetSchema(ctx, tableName)
+func (c *Conn) SetSchema(ctx context.Context, tableName string, sc *driver.Schema) error {
+	if err := c.checkDrop(ctx, "SetSchema", tableName); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		return c.replaceSchema(ctx, tableName, sc)
+	})
+}
+
+// replaceSchema changes or recreates a table as SetSchema does.
+func (c *Conn) replaceSchema(ctx context.Context, tableName string, sc *driver.Schema) error {
+	if err := c.checkNotView(ctx, "SetSchema", tableName); err != nil {
+		return err
+	}
+	if c.opts != nil && c.opts.SchemaChange != SchemaChangeRecreate {
+		exists, err := tableExistsDB(ctx, c.db, tableName)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return c.changeSchema(ctx, tableName, sc)
+		}
+	}
+	// the partitioning is read first, as a backup may rename the table aside
+	p, err := c.tablePartitioning(ctx, tableName)
 	if err != nil {
-		return nil, err
+		return err
 	}
+	if _, err := c.backupTable(ctx, tableName, c.backupMode()); err != nil {
+		return err
+	}
+	return c.setSchema(ctx, tableName, sc, p)
+}
 
-	resultRows, err := selectRowsDB(c.db, tableName)
+// changeSchema changes an existing table by the schema change mode of c, keeping its rows.
+func (c *Conn) changeSchema(ctx context.Context, tableName string, sc *driver.Schema) error {
+	switch c.opts.SchemaChange {
+	case SchemaChangeOnline:
+		return c.changeSchemaOnline(ctx, tableName, sc, c.opts.OnlineSchemaChange)
+	case SchemaChangeAlter:
+		// the table is altered in place, so it cannot be renamed aside
+		if _, err := c.backupTable(ctx, tableName, copyBackupMode(c.backupMode())); err != nil {
+			return err
+		}
+		return c.alterTable(ctx, tableName, sc, c.opts.Alter)
+	default:
+		return fmt.Errorf("unknown schema change mode: %s", c.opts.SchemaChange)
+	}
+}
+
+// setSchema recreates a table partitioned by p, unless p is nil.
+func (c *Conn) setSchema(ctx context.Context, tableName string, sc *driver.Schema, p *Partitioning) error {
+	return c.retry(ctx, func(ctx context.Context) error {
+		if err := dropTableDB(ctx, c.db, tableName); err != nil {
+			return err
+		}
+		return createPartitionedTableDB(ctx, c.db, sc, p)
+	})
+}
+
+func (c *Conn) GetRows(ctx context.Context, tableName string) ([]*driver.Row, error) {
+	var rows []*driver.Row
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		db := c.reader(ctx)
+		schema, err := getSchemaDB(ctx, db, tableName)
+		if err != nil {
+			return err
+		}
+		rows, err = selectSchemaRowsDB(ctx, db, schema, c.maskingRules[tableName], "")
+		return err
+	})
+	return rows, err
+}
+
+func selectSchemaRowsDB(ctx context.Context, db queryer, schema *driver.Schema, masks map[string]MaskFunc, where string, args ...interface{}) ([]*driver.Row, error) {
+	resultRows, err := selectRowsWhereDB(ctx, db, schema.Name, where, args...)
 	if err != nil {
 		return nil, err
 	}
 	defer resultRows.Close()
+	return scanRows(resultRows, schema, masks)
+}
 
+func scanRows(resultRows *sql.Rows, schema *driver.Schema, masks map[string]MaskFunc) ([]*driver.Row, error) {
 	var rows []*driver.Row
 	for resultRows.Next() {
 		rowValues := make(driver.RowValues, len(schema.Columns))
@@ -134,8 +297,18 @@ func (c *mysqlConn) GetRows(ctx context.Context, tableName string) ([]*driver.Ro
 		}
 		for i, col := range schema.Columns {
 			val := reflect.ValueOf(ptrs[i]).Elem().Interface()
+			if mask, ok := masks[col.Name]; ok {
+				masked, err := maskValue(mask, col, val)
+				if err != nil {
+					return nil, err
+				}
+				val = masked
+			}
 			colValue := &driver.GenericColumnValue{Column: col, Value: val}
 			rowValues[col.Name] = colValue
+			if schema.PrimaryKey == nil {
+				continue
+			}
 			for i := range schema.PrimaryKey.ColumnNames {
 				if schema.PrimaryKey.ColumnNames[i] == col.Name {
 					key := schema.PrimaryKey.String()
@@ -145,24 +318,61 @@ func (c *mysqlConn) GetRows(ctx context.Context, tableName string) ([]*driver.Ro
 		}
 		rows = append(rows, &driver.Row{GroupByKey: rowValuesGroupByKey, Values: rowValues})
 	}
+	if err := resultRows.Err(); err != nil {
+		return nil, err
+	}
 	return rows, nil
 }
 
-func (c *mysqlConn) SetRows(ctx context.Context, tableName string, rows []*driver.Row) error {
-	sc, err := c.GetSchema(ctx, tableName)
+func (c *Conn) SetRows(ctx context.Context, tableName string, rows []*driver.Row) error {
+	if err := c.checkDrop(ctx, "SetRows", tableName); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		return c.replaceRows(ctx, tableName, rows)
+	})
+}
+
+// replaceRows recreates a table with rows as SetRows does.
+func (c *Conn) replaceRows(ctx context.Context, tableName string, rows []*driver.Row) error {
+	tableType, err := getTableTypeDB(ctx, c.db, tableName)
 	if err != nil {
 		return err
 	}
+	if tableType == tableTypeView {
+		return c.replaceViewRows(ctx, tableName, rows)
+	}
 
-	dropTableDB(c.db, tableName)
-	createTableDB(c.db, sc)
+	// the table is recreated from the primary, as a replica may lag behind it
+	sc, err := getSchemaDB(ctx, c.db, tableName)
+	if err != nil {
+		return err
+	}
+	p, err := c.tablePartitioning(ctx, tableName)
+	if err != nil {
+		return err
+	}
+	// the schema is read first, as a backup may rename the table aside
+	if _, err := c.backupTable(ctx, tableName, c.backupMode()); err != nil {
+		return err
+	}
 
-	for _, row := range rows {
-		_, err := insertRowDB(c.db, tableName, row)
-		if err != nil {
+	// the table is recreated, so a failed attempt leaves nothing behind for the next one
+	return c.retry(ctx, func(ctx context.Context) error {
+		if err := dropTableDB(ctx, c.db, tableName); err != nil {
+			return err
+		}
+		if err := createPartitionedTableDB(ctx, c.db, sc, p); err != nil {
 			return err
 		}
-	}
 
-	return nil
+		for _, row := range rows {
+			_, err := insertRowDB(ctx, c.db, tableName, row)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
 }
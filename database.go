@@ -0,0 +1,164 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// DatabaseSchema is a snapshot of every table schema in a database.
+type DatabaseSchema struct {
+	Schemas     []*driver.Schema
+	ForeignKeys []*ForeignKey
+	Views       []*View
+	// Partitionings are those of the partitioned tables.
+	Partitionings []*Partitioning
+}
+
+func (ds *DatabaseSchema) schema(tableName string) *driver.Schema {
+	for _, sc := range ds.Schemas {
+		if sc.Name == tableName {
+			return sc
+		}
+	}
+	return nil
+}
+
+func (ds *DatabaseSchema) partitioning(tableName string) *Partitioning {
+	for _, p := range ds.Partitionings {
+		if p.TableName == tableName {
+			return p
+		}
+	}
+	return nil
+}
+
+func (ds *DatabaseSchema) tableNames() []string {
+	names := make([]string, len(ds.Schemas))
+	for i, sc := range ds.Schemas {
+		names[i] = sc.Name
+	}
+	return names
+}
+
+// GetDatabaseSchema reads the schemas of all tables in the connected database with a single query.
+func (c *Conn) GetDatabaseSchema(ctx context.Context) (*DatabaseSchema, error) {
+	var result *DatabaseSchema
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getDatabaseSchema(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (c *Conn) getDatabaseSchema(ctx context.Context) (*DatabaseSchema, error) {
+	// every part of the schema is read from one connection, so that they agree
+	db := c.reader(ctx)
+	rows, err := getDatabaseInformationSchemaDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	ds := &DatabaseSchema{}
+	var schema *driver.Schema
+	for rows.Next() {
+		var tableName string
+		var columnName string
+		var ordinalPosition int
+		var columnType string
+		var columnKey string
+		var isNullable string
+		var extra string
+		if err := rows.Scan(&tableName, &columnName, &ordinalPosition, &columnType, &columnKey, &isNullable, &extra); err != nil {
+			return nil, err
+		}
+
+		// rows are ordered by table, so a new name starts a new schema
+		if schema == nil || schema.Name != tableName {
+			schema = &driver.Schema{Name: tableName}
+			ds.Schemas = append(ds.Schemas, schema)
+		}
+		if err := appendColumn(schema, columnName, ordinalPosition, columnType, columnKey, isNullable, extra); err != nil {
+			return nil, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	fks, err := c.getForeignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	ds.ForeignKeys = fks
+
+	if ds.Views, err = c.getViews(ctx, db, ""); err != nil {
+		return nil, err
+	}
+	if ds.Partitionings, err = getPartitionings(ctx, db, ""); err != nil {
+		return nil, err
+	}
+	return ds, nil
+}
+
+// SetDatabaseSchema recreates every table of ds in foreign key dependency order, and then its views.
+// Existing tables with the same names are dropped beforehand.
+func (c *Conn) SetDatabaseSchema(ctx context.Context, ds *DatabaseSchema) error {
+	if err := c.checkDrop(ctx, "SetDatabaseSchema", ds.tableNames()...); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, ds.tableNames(), func(ctx context.Context) error {
+		// tables are dropped first, so a failed attempt leaves nothing behind for the next one
+		return c.retry(ctx, func(ctx context.Context) error {
+			return c.setDatabaseSchema(ctx, ds)
+		})
+	})
+}
+
+func (c *Conn) setDatabaseSchema(ctx context.Context, ds *DatabaseSchema) error {
+	plan := planLoad(ds.tableNames(), ds.ForeignKeys)
+
+	// drop dependents first so that no remaining table references a dropped one
+	for _, tableName := range plan.DeleteOrder {
+		if err := dropTableDB(ctx, c.db, tableName); err != nil {
+			return err
+		}
+	}
+
+	created := make(map[string]bool, len(plan.LoadOrder))
+	var deferred []*ForeignKey
+	for _, tableName := range plan.LoadOrder {
+		sc := ds.schema(tableName)
+		if sc == nil {
+			return errors.New("schema not found: " + tableName)
+		}
+		if err := c.setSchema(ctx, tableName, sc, ds.partitioning(tableName)); err != nil {
+			return err
+		}
+		created[tableName] = true
+
+		for _, fk := range ds.ForeignKeys {
+			if fk.TableName != tableName {
+				continue
+			}
+			// keys within a cycle reference tables which are not created yet
+			if !created[fk.ReferencedTableName] {
+				deferred = append(deferred, fk)
+				continue
+			}
+			if err := addForeignKeyDB(ctx, c.db, fk); err != nil {
+				return err
+			}
+		}
+	}
+	for _, fk := range deferred {
+		if err := addForeignKeyDB(ctx, c.db, fk); err != nil {
+			return err
+		}
+	}
+	// views select from the tables, so they come last
+	return c.setViews(ctx, ds.Views)
+}
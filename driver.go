@@ -2,6 +2,11 @@ package mysql
 
 import (
 	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	gomysql "github.com/go-sql-driver/mysql"
 
 	"github.com/go-tamate/tamate"
 	"github.com/go-tamate/tamate/driver"
@@ -12,7 +17,44 @@ const driverName = "mysql"
 type mysqlDriver struct{}
 
 func (md *mysqlDriver) Open(ctx context.Context, dsn string) (driver.Conn, error) {
-	return newMySQLConn(dsn)
+	dsn, opts, err := parseDSNOptions(dsn)
+	if err != nil {
+		return nil, err
+	}
+	conn, err := newMySQLConnWithOptions(dsn, opts)
+	if err != nil {
+		return nil, err
+	}
+	return conn, nil
+}
+
+// NewConn wraps a pool opened by the caller with go-sql-driver, with opts or the defaults when nil.
+// Options configuring the pool, its sessions or replicas are refused, as the caller configures the pool.
+// Closing the returned connection leaves db open.
+func NewConn(ctx context.Context, db *sql.DB, opts *Options) (*Conn, error) {
+	if db == nil {
+		return nil, errors.New("db is nil")
+	}
+	o := &Options{}
+	if opts != nil {
+		*o = *opts
+	}
+	if name := o.poolOption(); name != "" {
+		return nil, fmt.Errorf("%s cannot be set on a pool opened by the caller", name)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		return nil, err
+	}
+	return &Conn{db: db, opts: o}, nil
+}
+
+// NewConnFromConfig opens a pool from cfg. TLS configs and dials are referred to by the names
+// they are registered under with go-sql-driver.
+func NewConnFromConfig(ctx context.Context, cfg *gomysql.Config, opts *Options) (*Conn, error) {
+	if cfg == nil {
+		return nil, errors.New("config is nil")
+	}
+	return Open(ctx, cfg.FormatDSN(), opts)
 }
 
 func init() {
@@ -1,13 +1,15 @@
 package mysql
 
 import (
+	"context"
+	"database/sql"
 	"fmt"
 	"testing"
 
 	"github.com/go-tamate/tamate"
 	"github.com/stretchr/testify/assert"
 
-	_ "github.com/go-sql-driver/mysql"
+	gomysql "github.com/go-sql-driver/mysql"
 )
 
 const (
@@ -36,3 +38,44 @@ func Test_Open(t *testing.T) {
 	}()
 	assert.NoError(t, err)
 }
+
+func Test_NewConn(t *testing.T) {
+	// before
+	assert.NoError(t, dropDatabase(DriverTestUser, DriverTestPassword, DriverTestDBName))
+	assert.NoError(t, createDatabase(DriverTestUser, DriverTestPassword, DriverTestDBName))
+
+	dsn := fmt.Sprintf("%s:%s@/%s", DriverTestUser, DriverTestPassword, DriverTestDBName)
+	db, err := sql.Open(driverName, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer db.Close()
+
+	_, err = NewConn(context.Background(), db, &Options{MaxOpenConns: 2})
+	assert.Error(t, err, "the pool is configured by the caller")
+
+	conn, err := NewConn(context.Background(), db, &Options{ReadOnly: true})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.IsType(t, &ReadOnlyError{}, conn.checkWrite("SetRows"))
+	assert.NoError(t, conn.Close())
+	// the pool belongs to the caller, so it is still open
+	assert.NoError(t, db.Ping())
+}
+
+func Test_NewConnFromConfig(t *testing.T) {
+	// before
+	assert.NoError(t, dropDatabase(DriverTestUser, DriverTestPassword, DriverTestDBName))
+	assert.NoError(t, createDatabase(DriverTestUser, DriverTestPassword, DriverTestDBName))
+
+	cfg := gomysql.NewConfig()
+	cfg.User = DriverTestUser
+	cfg.Passwd = DriverTestPassword
+	cfg.DBName = DriverTestDBName
+	conn, err := NewConnFromConfig(context.Background(), cfg, &Options{MaxOpenConns: 2})
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NoError(t, conn.Close())
+}
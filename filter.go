@@ -0,0 +1,114 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// Operator compares a column with the value of a Filter.
+type Operator string
+
+const (
+	OperatorEqual          Operator = "="
+	OperatorNotEqual       Operator = "<>"
+	OperatorLess           Operator = "<"
+	OperatorLessOrEqual    Operator = "<="
+	OperatorGreater        Operator = ">"
+	OperatorGreaterOrEqual Operator = ">="
+	OperatorLike           Operator = "LIKE"
+	OperatorIn             Operator = "IN"
+	OperatorIsNull         Operator = "IS NULL"
+	OperatorIsNotNull      Operator = "IS NOT NULL"
+)
+
+// Filter restricts rows to those whose column compares to Value by Operator.
+// Value must be a slice for OperatorIn and is ignored for the NULL operators.
+type Filter struct {
+	Column   string
+	Operator Operator
+	Value    interface{}
+}
+
+// Order sorts rows by Column, in descending order when Descending is set.
+type Order struct {
+	Column     string
+	Descending bool
+}
+
+// RowsQuery selects a slice of a table. Filters are combined with AND and a zero Limit means no limit.
+type RowsQuery struct {
+	Filters []*Filter
+	OrderBy []*Order
+	Limit   int
+	// Partitions select rows of these partitions only.
+	Partitions []string
+}
+
+// GetRowsWithQuery reads the rows of a table selected by rq. A nil rq reads all of them.
+func (c *Conn) GetRowsWithQuery(ctx context.Context, tableName string, rq *RowsQuery) ([]*driver.Row, error) {
+	if rq == nil {
+		rq = &RowsQuery{}
+	}
+	var result []*driver.Row
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getRowsWithQuery(ctx, tableName, rq)
+		return err
+	})
+	return result, err
+}
+
+func (c *Conn) getRowsWithQuery(ctx context.Context, tableName string, rq *RowsQuery) ([]*driver.Row, error) {
+	db := c.reader(ctx)
+	schema, err := getSchemaDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	resultRows, err := selectRowsByQueryDB(ctx, db, schema, rq)
+	if err != nil {
+		return nil, err
+	}
+	defer resultRows.Close()
+	return scanRows(resultRows, schema, c.maskingRules[tableName])
+}
+
+func hasColumn(sc *driver.Schema, columnName string) bool {
+	for _, col := range sc.Columns {
+		if col.Name == columnName {
+			return true
+		}
+	}
+	return false
+}
+
+func validateRowsQuery(sc *driver.Schema, rq *RowsQuery) error {
+	for _, f := range rq.Filters {
+		if !hasColumn(sc, f.Column) {
+			return fmt.Errorf("column not found: %s", f.Column)
+		}
+		switch f.Operator {
+		case OperatorEqual, OperatorNotEqual, OperatorLess, OperatorLessOrEqual, OperatorGreater, OperatorGreaterOrEqual,
+			OperatorLike, OperatorIn, OperatorIsNull, OperatorIsNotNull:
+		default:
+			return fmt.Errorf("unsupported operator: %s", f.Operator)
+		}
+	}
+	for _, o := range rq.OrderBy {
+		if !hasColumn(sc, o.Column) {
+			return fmt.Errorf("column not found: %s", o.Column)
+		}
+	}
+	if rq.Limit < 0 {
+		return fmt.Errorf("invalid limit: %d", rq.Limit)
+	}
+	for _, name := range rq.Partitions {
+		if name == "" || strings.Contains(name, "`") {
+			return fmt.Errorf("invalid partition name: %s", name)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GenerateSelectRowsByQuery(t *testing.T) {
+	since := time.Date(2019, 3, 1, 0, 0, 0, 0, time.UTC)
+	sc := &driver.Schema{
+		Name: "example",
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("updated_at", 2, driver.ColumnTypeDatetime, true, false),
+		},
+	}
+
+	q, args, err := generateSelectRowsByQuery(sc, &RowsQuery{
+		Filters: []*Filter{
+			&Filter{Column: "updated_at", Operator: OperatorGreater, Value: since},
+			&Filter{Column: "id", Operator: OperatorIn, Value: []int{1, 2}},
+			&Filter{Column: "name", Operator: OperatorIsNotNull},
+		},
+		OrderBy: []*Order{
+			&Order{Column: "updated_at", Descending: true},
+			&Order{Column: "id"},
+		},
+		Limit: 10,
+	})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT * FROM `example` WHERE `updated_at` > ? AND `id` IN (?, ?) AND `name` IS NOT NULL ORDER BY `updated_at` DESC, `id` LIMIT 10", q)
+		assert.Equal(t, []interface{}{since, 1, 2}, args)
+	}
+
+	q, args, err = generateSelectRowsByQuery(sc, &RowsQuery{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT * FROM `example`", q)
+		assert.Empty(t, args)
+	}
+
+	_, _, err = generateSelectRowsByQuery(sc, &RowsQuery{Filters: []*Filter{&Filter{Column: "id; DROP TABLE example", Operator: OperatorEqual, Value: 1}}})
+	assert.EqualError(t, err, "column not found: id; DROP TABLE example")
+
+	_, _, err = generateSelectRowsByQuery(sc, &RowsQuery{Filters: []*Filter{&Filter{Column: "id", Operator: "= 1 OR 1 =", Value: 1}}})
+	assert.EqualError(t, err, "unsupported operator: = 1 OR 1 =")
+}
@@ -0,0 +1,259 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ForeignKey is a foreign key constraint of a table in the connected database.
+type ForeignKey struct {
+	Name                  string
+	TableName             string
+	ColumnNames           []string
+	ReferencedTableName   string
+	ReferencedColumnNames []string
+	OnUpdate              string
+	OnDelete              string
+}
+
+func (fk *ForeignKey) String() string {
+	return fmt.Sprintf("%s:%s(%s)->%s(%s)", fk.Name, fk.TableName, strings.Join(fk.ColumnNames, ","), fk.ReferencedTableName, strings.Join(fk.ReferencedColumnNames, ","))
+}
+
+func (c *Conn) GetForeignKeys(ctx context.Context) ([]*ForeignKey, error) {
+	var result []*ForeignKey
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getForeignKeys(ctx, c.reader(ctx))
+		return err
+	})
+	return result, err
+}
+
+func (c *Conn) getForeignKeys(ctx context.Context, db queryer) ([]*ForeignKey, error) {
+	rows, err := getForeignKeysDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanForeignKeys(rows)
+}
+
+func scanForeignKeys(rows *sql.Rows) ([]*ForeignKey, error) {
+	var fks []*ForeignKey
+	var last *ForeignKey
+	for rows.Next() {
+		var constraintName string
+		var tableName string
+		var columnName string
+		var referencedTableName string
+		var referencedColumnName string
+		var updateRule string
+		var deleteRule string
+		if err := rows.Scan(&constraintName, &tableName, &columnName, &referencedTableName, &referencedColumnName, &updateRule, &deleteRule); err != nil {
+			return nil, err
+		}
+
+		// rows are ordered by table and constraint, so multi-column keys are adjacent
+		if last == nil || last.TableName != tableName || last.Name != constraintName {
+			last = &ForeignKey{
+				Name:                constraintName,
+				TableName:           tableName,
+				ReferencedTableName: referencedTableName,
+				OnUpdate:            updateRule,
+				OnDelete:            deleteRule,
+			}
+			fks = append(fks, last)
+		}
+		last.ColumnNames = append(last.ColumnNames, columnName)
+		last.ReferencedColumnNames = append(last.ReferencedColumnNames, referencedColumnName)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return fks, nil
+}
+
+// ForeignKeyCycleError is returned when tables can not be ordered because their foreign keys form cycles.
+type ForeignKeyCycleError struct {
+	Cycles [][]string
+}
+
+func (e *ForeignKeyCycleError) Error() string {
+	cycles := make([]string, len(e.Cycles))
+	for i, cycle := range e.Cycles {
+		cycles[i] = "(" + strings.Join(cycle, ", ") + ")"
+	}
+	return "foreign key cycle between tables: " + strings.Join(cycles, ", ")
+}
+
+// LoadPlan is the order in which related tables can be written without violating foreign keys.
+// Tables in Cycles reference each other and are ordered by name among themselves.
+type LoadPlan struct {
+	LoadOrder   []string
+	DeleteOrder []string
+	Cycles      [][]string
+}
+
+func (p *LoadPlan) err() error {
+	if len(p.Cycles) > 0 {
+		return &ForeignKeyCycleError{Cycles: p.Cycles}
+	}
+	return nil
+}
+
+func (c *Conn) PlanLoad(ctx context.Context, tableNames []string) (*LoadPlan, error) {
+	fks, err := c.GetForeignKeys(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return planLoad(tableNames, fks), nil
+}
+
+// planLoad sorts tableNames so that every table comes after the tables it references.
+// Self references and references to tables outside of tableNames are ignored.
+func planLoad(tableNames []string, fks []*ForeignKey) *LoadPlan {
+	g := newTableGraph(tableNames, fks)
+	components := g.stronglyConnectedComponents()
+
+	// order the components topologically, breaking ties by name
+	componentOf := make(map[string]int, len(tableNames))
+	for i, component := range components {
+		for _, n := range component {
+			componentOf[n] = i
+		}
+	}
+	inDegree := make([]int, len(components))
+	dependents := make([][]int, len(components))
+	for _, from := range g.nodes {
+		for _, to := range g.edges[from] {
+			cf, ct := componentOf[from], componentOf[to]
+			if cf == ct {
+				continue
+			}
+			dependents[cf] = append(dependents[cf], ct)
+			inDegree[ct]++
+		}
+	}
+
+	var ready []int
+	for i := range components {
+		if inDegree[i] == 0 {
+			ready = append(ready, i)
+		}
+	}
+	plan := &LoadPlan{}
+	for len(ready) > 0 {
+		sort.Slice(ready, func(i, j int) bool {
+			return components[ready[i]][0] < components[ready[j]][0]
+		})
+		i := ready[0]
+		ready = ready[1:]
+
+		plan.LoadOrder = append(plan.LoadOrder, components[i]...)
+		if len(components[i]) > 1 {
+			plan.Cycles = append(plan.Cycles, components[i])
+		}
+		for _, d := range dependents[i] {
+			inDegree[d]--
+			if inDegree[d] == 0 {
+				ready = append(ready, d)
+			}
+		}
+	}
+
+	plan.DeleteOrder = make([]string, len(plan.LoadOrder))
+	for i, n := range plan.LoadOrder {
+		plan.DeleteOrder[len(plan.LoadOrder)-1-i] = n
+	}
+	sort.Slice(plan.Cycles, func(i, j int) bool {
+		return plan.Cycles[i][0] < plan.Cycles[j][0]
+	})
+	return plan
+}
+
+// tableGraph has an edge from every referenced table to the tables referencing it.
+type tableGraph struct {
+	nodes []string
+	edges map[string][]string
+}
+
+func newTableGraph(tableNames []string, fks []*ForeignKey) *tableGraph {
+	g := &tableGraph{edges: make(map[string][]string)}
+	known := make(map[string]bool, len(tableNames))
+	for _, n := range tableNames {
+		if known[n] {
+			continue
+		}
+		known[n] = true
+		g.nodes = append(g.nodes, n)
+	}
+	sort.Strings(g.nodes)
+
+	seen := make(map[[2]string]bool)
+	for _, fk := range fks {
+		from, to := fk.ReferencedTableName, fk.TableName
+		if from == to || !known[from] || !known[to] || seen[[2]string{from, to}] {
+			continue
+		}
+		seen[[2]string{from, to}] = true
+		g.edges[from] = append(g.edges[from], to)
+	}
+	return g
+}
+
+// stronglyConnectedComponents returns the components of g by Tarjan's algorithm, each sorted by name.
+func (g *tableGraph) stronglyConnectedComponents() [][]string {
+	var (
+		index      = 0
+		indexes    = make(map[string]int, len(g.nodes))
+		lowlinks   = make(map[string]int, len(g.nodes))
+		onStack    = make(map[string]bool, len(g.nodes))
+		stack      []string
+		components [][]string
+		visit      func(n string)
+	)
+	visit = func(n string) {
+		indexes[n] = index
+		lowlinks[n] = index
+		index++
+		stack = append(stack, n)
+		onStack[n] = true
+
+		for _, m := range g.edges[n] {
+			if _, visited := indexes[m]; !visited {
+				visit(m)
+				if lowlinks[m] < lowlinks[n] {
+					lowlinks[n] = lowlinks[m]
+				}
+			} else if onStack[m] && indexes[m] < lowlinks[n] {
+				lowlinks[n] = indexes[m]
+			}
+		}
+
+		if lowlinks[n] == indexes[n] {
+			var component []string
+			for {
+				m := stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+				onStack[m] = false
+				component = append(component, m)
+				if m == n {
+					break
+				}
+			}
+			sort.Strings(component)
+			components = append(components, component)
+		}
+	}
+
+	for _, n := range g.nodes {
+		if _, visited := indexes[n]; !visited {
+			visit(n)
+		}
+	}
+	return components
+}
@@ -0,0 +1,34 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PlanLoad(t *testing.T) {
+	fks := []*ForeignKey{
+		&ForeignKey{Name: "fk_orders_users", TableName: "orders", ColumnNames: []string{"user_id"}, ReferencedTableName: "users", ReferencedColumnNames: []string{"id"}},
+		&ForeignKey{Name: "fk_items_orders", TableName: "items", ColumnNames: []string{"order_id"}, ReferencedTableName: "orders", ReferencedColumnNames: []string{"id"}},
+		&ForeignKey{Name: "fk_users_users", TableName: "users", ColumnNames: []string{"invited_by"}, ReferencedTableName: "users", ReferencedColumnNames: []string{"id"}},
+	}
+
+	plan := planLoad([]string{"items", "orders", "tags", "users"}, fks)
+	assert.Equal(t, []string{"tags", "users", "orders", "items"}, plan.LoadOrder)
+	assert.Equal(t, []string{"items", "orders", "users", "tags"}, plan.DeleteOrder)
+	assert.Empty(t, plan.Cycles)
+	assert.NoError(t, plan.err())
+}
+
+func Test_PlanLoadCycle(t *testing.T) {
+	fks := []*ForeignKey{
+		&ForeignKey{Name: "fk_a_b", TableName: "a", ColumnNames: []string{"b_id"}, ReferencedTableName: "b", ReferencedColumnNames: []string{"id"}},
+		&ForeignKey{Name: "fk_b_a", TableName: "b", ColumnNames: []string{"a_id"}, ReferencedTableName: "a", ReferencedColumnNames: []string{"id"}},
+		&ForeignKey{Name: "fk_c_b", TableName: "c", ColumnNames: []string{"b_id"}, ReferencedTableName: "b", ReferencedColumnNames: []string{"id"}},
+	}
+
+	plan := planLoad([]string{"c", "b", "a"}, fks)
+	assert.Equal(t, []string{"a", "b", "c"}, plan.LoadOrder)
+	assert.Equal(t, [][]string{{"a", "b"}}, plan.Cycles)
+	assert.EqualError(t, plan.err(), "foreign key cycle between tables: (a, b)")
+}
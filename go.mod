@@ -5,6 +5,12 @@ go 1.12
 require (
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/go-tamate/tamate v0.3.0
+	github.com/pingcap/errors v0.11.4 // indirect
+	github.com/satori/go.uuid v1.2.0 // indirect
+	github.com/shopspring/decimal v1.4.0 // indirect
+	github.com/siddontang/go v0.0.0-20180604090527-bdc77568d726 // indirect
+	github.com/siddontang/go-log v0.0.0-20180807004314-8d05993dda07 // indirect
+	github.com/siddontang/go-mysql v0.0.0-20190118051307-00086da2c732
 	github.com/stretchr/testify v1.3.0
 	google.golang.org/appengine v1.4.0 // indirect
 )
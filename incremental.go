@@ -0,0 +1,270 @@
+package mysql
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+const defaultIncrementalBatchSize = 1000
+
+// Watermark is where an incremental read stopped: the watermark column value and the primary key of the last row.
+type Watermark struct {
+	Value      interface{}
+	PrimaryKey []interface{}
+}
+
+// WatermarkStore persists watermarks between incremental reads.
+// LoadWatermark returns nil when nothing was saved under key yet.
+type WatermarkStore interface {
+	LoadWatermark(ctx context.Context, key string) (*Watermark, error)
+	SaveWatermark(ctx context.Context, key string, wm *Watermark) error
+}
+
+type IncrementalOptions struct {
+	// Column is a timestamp or monotonically increasing column. Rows where it is NULL are never read.
+	// DATETIME and TIMESTAMP columns need parseTime=true in the DSN.
+	Column string
+	// BatchSize limits the rows passed to apply at once. It defaults to 1000.
+	BatchSize int
+	Store     WatermarkStore
+	// Key identifies the watermark in Store. It defaults to the table name.
+	Key string
+}
+
+// GetRowsSince reads up to limit rows changed after wm in the order of column and the primary key,
+// along with the watermark of the last row. A nil wm reads from the beginning.
+// The returned watermark is wm itself when there are no more rows.
+func (c *Conn) GetRowsSince(ctx context.Context, tableName, column string, wm *Watermark, limit int) ([]*driver.Row, *Watermark, error) {
+	var sc *driver.Schema
+	var rows []*driver.Row
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		db := c.reader(ctx)
+		var err error
+		if sc, err = getSchemaDB(ctx, db, tableName); err != nil {
+			return err
+		}
+		if !hasColumn(sc, column) {
+			return fmt.Errorf("column not found: %s", column)
+		}
+		resultRows, err := selectRowsSinceDB(ctx, db, sc, column, wm, limit)
+		if err != nil {
+			return err
+		}
+		defer resultRows.Close()
+		rows, err = scanRows(resultRows, sc, nil)
+		return err
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(rows) == 0 {
+		return nil, wm, nil
+	}
+
+	next, err := watermarkOf(sc, column, rows[len(rows)-1])
+	if err != nil {
+		return nil, nil, err
+	}
+	// the watermark is taken from the original values, so masking comes last
+	if err := maskRows(c.maskingRules[tableName], rows); err != nil {
+		return nil, nil, err
+	}
+	return rows, next, nil
+}
+
+// SyncChangedRows passes the rows changed since the stored watermark to apply in batches,
+// saving the watermark after every batch apply succeeds. Later runs continue where it stopped.
+func (c *Conn) SyncChangedRows(ctx context.Context, tableName string, opts *IncrementalOptions, apply func(rows []*driver.Row) error) error {
+	if opts == nil || opts.Column == "" || opts.Store == nil {
+		return errors.New("watermark column and store must be set")
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultIncrementalBatchSize
+	}
+	key := opts.Key
+	if key == "" {
+		key = tableName
+	}
+
+	wm, err := opts.Store.LoadWatermark(ctx, key)
+	if err != nil {
+		return err
+	}
+	for {
+		rows, next, err := c.GetRowsSince(ctx, tableName, opts.Column, wm, batchSize)
+		if err != nil {
+			return err
+		}
+		if len(rows) == 0 {
+			return nil
+		}
+		if err := apply(rows); err != nil {
+			return err
+		}
+		if err := opts.Store.SaveWatermark(ctx, key, next); err != nil {
+			return err
+		}
+		if len(rows) < batchSize {
+			return nil
+		}
+		wm = next
+	}
+}
+
+func watermarkOf(sc *driver.Schema, column string, row *driver.Row) (*Watermark, error) {
+	cv, ok := row.Values[column]
+	if !ok {
+		return nil, fmt.Errorf("column not found: %s", column)
+	}
+	v, err := sqlValue(cv.Value)
+	if err != nil {
+		return nil, err
+	}
+	wm := &Watermark{Value: v}
+	for _, name := range sc.PrimaryKey.ColumnNames {
+		cv, ok := row.Values[name]
+		if !ok {
+			return nil, fmt.Errorf("column not found: %s", name)
+		}
+		v, err := sqlValue(cv.Value)
+		if err != nil {
+			return nil, err
+		}
+		wm.PrimaryKey = append(wm.PrimaryKey, v)
+	}
+	return wm, nil
+}
+
+// typedValue keeps the Go type of a watermark value through JSON.
+type typedValue struct {
+	Type  string `json:"type"`
+	Value string `json:"value"`
+}
+
+func newTypedValue(v interface{}) (*typedValue, error) {
+	switch v := v.(type) {
+	case int64:
+		return &typedValue{Type: "int", Value: strconv.FormatInt(v, 10)}, nil
+	case float64:
+		return &typedValue{Type: "float", Value: strconv.FormatFloat(v, 'g', -1, 64)}, nil
+	case string:
+		return &typedValue{Type: "string", Value: v}, nil
+	case []byte:
+		return &typedValue{Type: "bytes", Value: base64.StdEncoding.EncodeToString(v)}, nil
+	case time.Time:
+		return &typedValue{Type: "time", Value: v.Format(time.RFC3339Nano)}, nil
+	default:
+		return nil, fmt.Errorf("unsupported watermark value: %T", v)
+	}
+}
+
+func (tv *typedValue) value() (interface{}, error) {
+	switch tv.Type {
+	case "int":
+		return strconv.ParseInt(tv.Value, 10, 64)
+	case "float":
+		return strconv.ParseFloat(tv.Value, 64)
+	case "string":
+		return tv.Value, nil
+	case "bytes":
+		return base64.StdEncoding.DecodeString(tv.Value)
+	case "time":
+		return time.Parse(time.RFC3339Nano, tv.Value)
+	default:
+		return nil, fmt.Errorf("unsupported watermark type: %s", tv.Type)
+	}
+}
+
+type watermarkJSON struct {
+	Value      *typedValue   `json:"value"`
+	PrimaryKey []*typedValue `json:"primary_key"`
+}
+
+func (wm *Watermark) MarshalJSON() ([]byte, error) {
+	value, err := newTypedValue(wm.Value)
+	if err != nil {
+		return nil, err
+	}
+	j := &watermarkJSON{Value: value}
+	for _, v := range wm.PrimaryKey {
+		tv, err := newTypedValue(v)
+		if err != nil {
+			return nil, err
+		}
+		j.PrimaryKey = append(j.PrimaryKey, tv)
+	}
+	return json.Marshal(j)
+}
+
+func (wm *Watermark) UnmarshalJSON(data []byte) error {
+	var j watermarkJSON
+	if err := json.Unmarshal(data, &j); err != nil {
+		return err
+	}
+	if j.Value == nil {
+		return errors.New("watermark value not found")
+	}
+	value, err := j.Value.value()
+	if err != nil {
+		return err
+	}
+	wm.Value = value
+	wm.PrimaryKey = nil
+	for _, tv := range j.PrimaryKey {
+		v, err := tv.value()
+		if err != nil {
+			return err
+		}
+		wm.PrimaryKey = append(wm.PrimaryKey, v)
+	}
+	return nil
+}
+
+// FileWatermarkStore saves every watermark as a JSON file in a directory.
+type FileWatermarkStore struct {
+	Dir string
+}
+
+func (s *FileWatermarkStore) path(key string) string {
+	return filepath.Join(s.Dir, url.PathEscape(key)+".json")
+}
+
+func (s *FileWatermarkStore) LoadWatermark(ctx context.Context, key string) (*Watermark, error) {
+	data, err := ioutil.ReadFile(s.path(key))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	wm := &Watermark{}
+	if err := json.Unmarshal(data, wm); err != nil {
+		return nil, err
+	}
+	return wm, nil
+}
+
+func (s *FileWatermarkStore) SaveWatermark(ctx context.Context, key string, wm *Watermark) error {
+	data, err := json.Marshal(wm)
+	if err != nil {
+		return err
+	}
+	// write aside and rename, so that a crash never leaves a partial watermark
+	tmp := s.path(key) + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path(key))
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GenerateSelectRowsSinceQuery(t *testing.T) {
+	sc := &driver.Schema{
+		Name: "example",
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"tenant_id", "id"},
+		},
+	}
+
+	q, err := generateSelectRowsSinceQuery(sc, "updated_at", false, 100)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT * FROM `example` WHERE `updated_at` IS NOT NULL ORDER BY `updated_at`, `tenant_id`, `id` LIMIT 100", q)
+	}
+
+	q, err = generateSelectRowsSinceQuery(sc, "updated_at", true, 100)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT * FROM `example` WHERE (`updated_at` > ? OR (`updated_at` = ? AND (`tenant_id`, `id`) > (?, ?))) ORDER BY `updated_at`, `tenant_id`, `id` LIMIT 100", q)
+	}
+
+	_, err = generateSelectRowsSinceQuery(&driver.Schema{Name: "nokey"}, "updated_at", false, 100)
+	assert.EqualError(t, err, "primary key not found: nokey")
+}
+
+func Test_FileWatermarkStore(t *testing.T) {
+	ctx := context.Background()
+	dir, err := ioutil.TempDir("", "tamate-mysql")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	store := &FileWatermarkStore{Dir: dir}
+
+	wm, err := store.LoadWatermark(ctx, "example")
+	assert.NoError(t, err)
+	assert.Nil(t, wm)
+
+	saved := &Watermark{
+		Value:      time.Date(2019, 3, 1, 12, 30, 0, 500, time.UTC),
+		PrimaryKey: []interface{}{int64(3), "a/b", []byte{0, 1}},
+	}
+	assert.NoError(t, store.SaveWatermark(ctx, "example", saved))
+	wm, err = store.LoadWatermark(ctx, "example")
+	if assert.NoError(t, err) {
+		assert.Equal(t, saved, wm)
+	}
+
+	assert.EqualError(t, store.SaveWatermark(ctx, "example", &Watermark{Value: int32(1)}), "json: error calling MarshalJSON for type *mysql.Watermark: unsupported watermark value: int32")
+}
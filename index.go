@@ -0,0 +1,123 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// Index is a secondary index of a table.
+type Index struct {
+	Name    string
+	Columns []IndexColumn
+	Unique  bool
+	// Type is FULLTEXT or SPATIAL for those indexes, and BTREE or empty for the others.
+	Type string
+}
+
+// IndexColumn is a column of an index. Length is the length of an indexed prefix, and 0 for the whole column.
+type IndexColumn struct {
+	Name   string
+	Length int
+}
+
+// special reports whether i is a FULLTEXT or SPATIAL index.
+func (i *Index) special() bool {
+	return strings.EqualFold(i.Type, "FULLTEXT") || strings.EqualFold(i.Type, "SPATIAL")
+}
+
+func (i *Index) equal(other *Index) bool {
+	if i.Unique != other.Unique || i.special() != other.special() || (i.special() && !strings.EqualFold(i.Type, other.Type)) {
+		return false
+	}
+	if len(i.Columns) != len(other.Columns) {
+		return false
+	}
+	for n, col := range i.Columns {
+		if col != other.Columns[n] {
+			return false
+		}
+	}
+	return true
+}
+
+// getIndexes reads the secondary indexes of a table in the order of their names.
+func getIndexes(ctx context.Context, db queryer, tableName string) ([]*Index, error) {
+	rows, err := getIndexesDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var indexes []*Index
+	for rows.Next() {
+		var name string
+		var nonUnique bool
+		var indexType string
+		var columnName sql.NullString
+		var subPart sql.NullInt64
+		if err := rows.Scan(&name, &nonUnique, &indexType, &columnName, &subPart); err != nil {
+			return nil, err
+		}
+		// a functional key part has no column, and its expression cannot be planned
+		if !columnName.Valid {
+			return nil, fmt.Errorf("functional index %s of %s is not supported", name, tableName)
+		}
+		if len(indexes) == 0 || indexes[len(indexes)-1].Name != name {
+			indexes = append(indexes, &Index{Name: name, Unique: !nonUnique, Type: indexType})
+		}
+		index := indexes[len(indexes)-1]
+		index.Columns = append(index.Columns, IndexColumn{Name: columnName.String, Length: int(subPart.Int64)})
+	}
+	return indexes, rows.Err()
+}
+
+// diffIndexes lists the alterations turning the live indexes of a table into the desired ones of sc.
+// Columns renamed by renames are renamed in the live indexes as the server does. An index changing
+// its definition is dropped and added again.
+func diffIndexes(tableName string, live, desired []*Index, sc *driver.Schema, renames map[string]string) ([]*Alteration, error) {
+	desiredByName := make(map[string]*Index, len(desired))
+	for _, index := range desired {
+		if strings.EqualFold(index.Name, "PRIMARY") || index.Name == "" {
+			return nil, fmt.Errorf("index of %s needs a name other than PRIMARY", tableName)
+		}
+		if _, ok := desiredByName[index.Name]; ok {
+			return nil, fmt.Errorf("index %s of %s is given twice", index.Name, tableName)
+		}
+		if len(index.Columns) == 0 {
+			return nil, fmt.Errorf("index %s of %s has no column", index.Name, tableName)
+		}
+		for _, col := range index.Columns {
+			if findColumn(sc, col.Name) == nil {
+				return nil, fmt.Errorf("column %s of index %s not found in %s", col.Name, index.Name, tableName)
+			}
+		}
+		desiredByName[index.Name] = index
+	}
+
+	var drops, adds []*Alteration
+	liveByName := make(map[string]*Index, len(live))
+	for _, index := range live {
+		renamed := *index
+		renamed.Columns = make([]IndexColumn, len(index.Columns))
+		for n, col := range index.Columns {
+			if to, ok := renames[col.Name]; ok {
+				col.Name = to
+			}
+			renamed.Columns[n] = col
+		}
+		liveByName[index.Name] = &renamed
+		if d, ok := desiredByName[index.Name]; !ok || !d.equal(&renamed) {
+			drops = append(drops, &Alteration{Kind: AlterDropIndex, PreviousIndex: index})
+		}
+	}
+	for _, index := range desired {
+		if l, ok := liveByName[index.Name]; !ok || !index.equal(l) {
+			adds = append(adds, &Alteration{Kind: AlterAddIndex, Index: index})
+		}
+	}
+	return append(drops, adds...), nil
+}
@@ -0,0 +1,70 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DiffIndexes(t *testing.T) {
+	sc := &driver.Schema{
+		Name: "user",
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("full_name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("email", 2, driver.ColumnTypeString, false, false),
+			driver.NewColumn("bio", 3, driver.ColumnTypeString, false, false),
+		},
+	}
+	live := []*Index{
+		{Name: "idx_email", Columns: []IndexColumn{{Name: "email", Length: 64}}, Type: "BTREE"},
+		{Name: "idx_name", Columns: []IndexColumn{{Name: "name", Length: 32}}, Type: "BTREE"},
+		{Name: "idx_old", Columns: []IndexColumn{{Name: "id"}, {Name: "email", Length: 64}}, Type: "BTREE"},
+	}
+	desired := []*Index{
+		{Name: "idx_bio", Columns: []IndexColumn{{Name: "bio"}}, Type: "FULLTEXT"},
+		{Name: "idx_email", Columns: []IndexColumn{{Name: "email", Length: 64}}, Unique: true},
+		// name is renamed to full_name, which the live index follows
+		{Name: "idx_name", Columns: []IndexColumn{{Name: "full_name", Length: 32}}},
+	}
+
+	alterations, err := diffIndexes("user", live, desired, sc, map[string]string{"name": "full_name"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	var clauses []string
+	for _, a := range alterations {
+		clause, err := a.clause()
+		if assert.NoError(t, err) {
+			clauses = append(clauses, clause)
+		}
+	}
+	assert.Equal(t, []string{
+		"DROP INDEX `idx_email`",
+		"DROP INDEX `idx_old`",
+		"ADD FULLTEXT INDEX `idx_bio` (`bio`)",
+		"ADD UNIQUE INDEX `idx_email` (`email`(64))",
+	}, clauses)
+
+	mysql8 := &ServerVersion{Major: 8, Minor: 0, Patch: 30}
+	for _, a := range alterations {
+		assert.Equal(t, AlterAlgorithmInplace, predictAlgorithm(mysql8, a, false))
+	}
+	assert.Equal(t, ChangeSafe, classifyAlteration(alterations[1], AlterAlgorithmInplace))
+	assert.Equal(t, ChangeLocking, classifyAlteration(alterations[2], AlterAlgorithmInplace))
+	assert.Equal(t, ChangeSafe, classifyAlteration(alterations[3], AlterAlgorithmInplace))
+	assert.Equal(t, "add index FULLTEXT INDEX `idx_bio` (`bio`)", describeAlteration(alterations[2]))
+
+	_, err = diffIndexes("user", live, []*Index{{Name: "idx_age", Columns: []IndexColumn{{Name: "age"}}}}, sc, nil)
+	assert.Error(t, err, "column missing from the new schema")
+	_, err = diffIndexes("user", live, []*Index{desired[0], desired[0]}, sc, nil)
+	assert.Error(t, err, "index given twice")
+	_, err = diffIndexes("user", live, []*Index{{Name: "PRIMARY", Columns: []IndexColumn{{Name: "id"}}}}, sc, nil)
+	assert.Error(t, err)
+
+	alterations, err = diffIndexes("user", live, nil, sc, nil)
+	if assert.NoError(t, err) {
+		assert.Len(t, alterations, 3)
+	}
+}
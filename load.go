@@ -0,0 +1,102 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// LoadOptions controls how LoadRows writes related tables.
+type LoadOptions struct {
+	// DisableForeignKeyChecks wraps the load in SET FOREIGN_KEY_CHECKS=0,
+	// which also allows tables whose foreign keys form cycles.
+	DisableForeignKeyChecks bool
+}
+
+// LoadRows replaces the rows of several tables in one transaction.
+// Existing rows are deleted in reverse dependency order and the new rows are inserted in dependency order.
+func (c *Conn) LoadRows(ctx context.Context, rowsByTable map[string][]*driver.Row, opts *LoadOptions) error {
+	if err := c.checkWrite("LoadRows"); err != nil {
+		return err
+	}
+	if opts == nil {
+		opts = &LoadOptions{}
+	}
+
+	tableNames := make([]string, 0, len(rowsByTable))
+	for tableName := range rowsByTable {
+		tableNames = append(tableNames, tableName)
+	}
+	sort.Strings(tableNames)
+
+	// the foreign keys are read from the primary the rows are written to
+	fks, err := c.getForeignKeys(ctx, c.db)
+	if err != nil {
+		return err
+	}
+	plan := planLoad(tableNames, fks)
+	if !opts.DisableForeignKeyChecks {
+		if err := plan.err(); err != nil {
+			return err
+		}
+	}
+
+	return c.withWriteLock(ctx, tableNames, func(ctx context.Context) error {
+		// the whole transaction is repeated, as a deadlock rolls all of it back
+		return c.retry(ctx, func(ctx context.Context) error {
+			return c.loadRows(ctx, plan, rowsByTable, opts)
+		})
+	})
+}
+
+func (c *Conn) loadRows(ctx context.Context, plan *LoadPlan, rowsByTable map[string][]*driver.Row, opts *LoadOptions) (err error) {
+	// session variables and the transaction have to share one connection
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if opts.DisableForeignKeyChecks {
+		if err := setForeignKeyChecksDB(ctx, conn, false); err != nil {
+			return err
+		}
+		// the connection goes back to the pool, so the checks are restored even when ctx is cancelled
+		defer func() {
+			if rerr := setForeignKeyChecksDB(context.Background(), conn, true); rerr != nil && err == nil {
+				err = rerr
+			}
+		}()
+	}
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := loadRowsTx(ctx, tx, plan, rowsByTable); err != nil {
+		if rerr := tx.Rollback(); rerr != nil {
+			return fmt.Errorf("%v (rollback: %v)", err, rerr)
+		}
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadRowsTx(ctx context.Context, tx *sql.Tx, plan *LoadPlan, rowsByTable map[string][]*driver.Row) error {
+	for _, tableName := range plan.DeleteOrder {
+		if err := deleteRowsDB(ctx, tx, tableName); err != nil {
+			return err
+		}
+	}
+	for _, tableName := range plan.LoadOrder {
+		for _, row := range rowsByTable[tableName] {
+			if _, err := insertRowDB(ctx, tx, tableName, row); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
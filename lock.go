@@ -0,0 +1,128 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// maxLockNameLen is the longest name GET_LOCK takes.
+const maxLockNameLen = 64
+
+// LockTimeoutError is returned when another session held an advisory lock for longer than the timeout.
+type LockTimeoutError struct {
+	Name    string
+	Timeout time.Duration
+}
+
+func (e *LockTimeoutError) Error() string {
+	return fmt.Sprintf("advisory lock %s not acquired within %v", e.Name, e.Timeout)
+}
+
+// advisoryLock is locks of GET_LOCK, which belong to the session holding them.
+type advisoryLock struct {
+	conn  *sql.Conn
+	names []string
+	// db and id kill the session when the locks cannot be released, so that no pooled connection keeps them.
+	db queryer
+	id int64
+}
+
+type writeLockContextKey struct{}
+
+// lockName names the advisory lock of an object, which is hashed when the name would be too long.
+func lockName(database, object string) string {
+	name := fmt.Sprintf("tamate:%s.%s", database, object)
+	if len(name) > maxLockNameLen {
+		name = "tamate:" + registryKey(database, object)
+	}
+	return name
+}
+
+// acquireLock waits for advisory locks no longer than timeout each, on a connection pinned until they are released.
+// Locks are taken in order of name, so that sessions taking several do not deadlock.
+func (c *Conn) acquireLock(ctx context.Context, timeout time.Duration, names ...string) (*advisoryLock, error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	id, err := getConnectionIDDB(ctx, conn)
+	if err != nil {
+		if cerr := conn.Close(); cerr != nil {
+			return nil, fmt.Errorf("%v (close: %v)", err, cerr)
+		}
+		return nil, err
+	}
+	l := &advisoryLock{conn: conn, db: c.db, id: id}
+	sorted := make([]string, len(names))
+	copy(sorted, names)
+	sort.Strings(sorted)
+	for _, name := range sorted {
+		acquired, err := getLockDB(ctx, conn, name, timeout)
+		if err == nil && !acquired {
+			err = &LockTimeoutError{Name: name, Timeout: timeout}
+		}
+		if err != nil {
+			if rerr := l.release(); rerr != nil {
+				return nil, fmt.Errorf("%v (release: %v)", err, rerr)
+			}
+			return nil, err
+		}
+		l.names = append(l.names, name)
+	}
+	return l, nil
+}
+
+// release releases the locks and returns their connection to the pool. It does not take the context
+// of the caller, as the locks have to be released even when that is cancelled. When they cannot be,
+// the session is killed to end them.
+func (l *advisoryLock) release() error {
+	ctx := context.Background()
+	var err error
+	for _, name := range l.names {
+		if rerr := releaseLockDB(ctx, l.conn, name); err == nil {
+			err = rerr
+		}
+	}
+	if err != nil {
+		if kerr := killConnectionDB(ctx, l.db, l.id); kerr != nil {
+			err = fmt.Errorf("%v (kill: %v)", err, kerr)
+		}
+	}
+	if cerr := l.conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// withWriteLock runs fn holding the advisory locks of tableNames, when Options.WriteLockTimeout is set.
+// Writes nested in a locked one run without locking again.
+func (c *Conn) withWriteLock(ctx context.Context, tableNames []string, fn func(ctx context.Context) error) (err error) {
+	if c.opts == nil || c.opts.WriteLockTimeout <= 0 || len(tableNames) == 0 || ctx.Value(writeLockContextKey{}) != nil {
+		return fn(ctx)
+	}
+	database, err := c.databaseName(ctx)
+	if err != nil {
+		return err
+	}
+	names := make([]string, len(tableNames))
+	for i, tableName := range tableNames {
+		names[i] = lockName(database, tableName)
+	}
+	lock, err := c.acquireLock(ctx, c.opts.WriteLockTimeout, names...)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := lock.release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+	return fn(context.WithValue(ctx, writeLockContextKey{}, true))
+}
+
+func (c *Conn) databaseName(ctx context.Context) (string, error) {
+	return getDatabaseNameDB(ctx, c.db)
+}
@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_LockName(t *testing.T) {
+	assert.Equal(t, "tamate:tamatest.schema_migrations", lockName("tamatest", "schema_migrations"))
+	name := lockName("tamatest", strings.Repeat("x", 64))
+	assert.Len(t, name, len("tamate:")+16)
+
+	_, opts, err := parseDSNOptions("root:pass@tcp(localhost:3306)/tamate?writeLockTimeout=3s")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 3*time.Second, opts.WriteLockTimeout)
+	}
+}
+
+func Test_WriteLock(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s?writeLockTimeout=1s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+
+	// Open connections
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	other, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer other.Close()
+	c := conn.(*Conn)
+
+	// Writing while another connection holds the lock
+	lock, err := other.(*Conn).acquireLock(ctx, time.Second, lockName(dbName, tableName))
+	if !assert.NoError(t, err) {
+		return
+	}
+	err = c.SetRows(ctx, tableName, nil)
+	assert.IsType(t, &LockTimeoutError{}, err)
+
+	// Writing after it is released
+	assert.NoError(t, lock.release())
+	assert.NoError(t, c.SetRows(ctx, tableName, nil))
+}
@@ -0,0 +1,271 @@
+package mysql
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/go-tamate/tamate/driver"
+)
+
+// MaskFunc replaces a value read from the database. NULL is passed as nil.
+type MaskFunc func(value interface{}) (interface{}, error)
+
+// MaskingRules maps table names to the masks of their columns.
+type MaskingRules map[string]map[string]MaskFunc
+
+// maxMaskedInt is the largest masked integer, so that masked values fit the INT columns this driver creates.
+const maxMaskedInt = math.MaxInt32
+
+var columnLengthPattern = regexp.MustCompile(`^(?:var)?char\((\d+)\)`)
+
+// SetMaskingRules masks the values of the given columns in every row read afterwards.
+// The columns have to exist, and masked values are fitted to them: integers are wrapped into the range
+// of the column and of INT, and strings are cut to the length of CHAR and VARCHAR columns.
+// Masks are not collision free, so columns of the primary key or of a unique index are refused,
+// as their masked rows could no longer be told apart or written back.
+func (c *Conn) SetMaskingRules(ctx context.Context, rules MaskingRules) error {
+	v, err := c.serverVersion(ctx)
+	if err != nil {
+		return err
+	}
+	fitted := make(MaskingRules, len(rules))
+	for tableName, masks := range rules {
+		defs, err := getColumnDefinitions(ctx, c.db, v, tableName)
+		if err != nil {
+			return err
+		}
+		if len(defs) == 0 {
+			return fmt.Errorf("masked table %s not found", tableName)
+		}
+		keys, err := uniqueKeyColumns(ctx, c.db, tableName)
+		if err != nil {
+			return err
+		}
+		fitted[tableName] = make(map[string]MaskFunc, len(masks))
+		for columnName, mask := range masks {
+			def, ok := defs[columnName]
+			if !ok {
+				return fmt.Errorf("masked column %s not found in %s", columnName, tableName)
+			}
+			if key, ok := keys[columnName]; ok {
+				return fmt.Errorf("masked column %s of %s is in the unique key %s, whose masked values may collide", columnName, tableName, key)
+			}
+			fitted[tableName][columnName] = fitMask(mask, def.ColumnType)
+		}
+	}
+	c.maskingRules = fitted
+	return nil
+}
+
+// uniqueKeyColumns maps the columns of the primary key and unique indexes of a table to the key they are in.
+func uniqueKeyColumns(ctx context.Context, db queryer, tableName string) (map[string]string, error) {
+	sc, err := getSchemaDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	indexes, err := getIndexes(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	keys := make(map[string]string)
+	for _, name := range primaryKeyNames(sc) {
+		keys[name] = "PRIMARY"
+	}
+	for _, index := range indexes {
+		if !index.Unique {
+			continue
+		}
+		for _, col := range index.Columns {
+			if _, ok := keys[col.Name]; !ok {
+				keys[col.Name] = index.Name
+			}
+		}
+	}
+	return keys, nil
+}
+
+// fitMask wraps mask so that its values fit a column of columnType.
+func fitMask(mask MaskFunc, columnType string) MaskFunc {
+	maxInt := integerColumnMax(columnType)
+	length := 0
+	if m := columnLengthPattern.FindStringSubmatch(strings.ToLower(columnType)); m != nil {
+		if n, err := strconv.Atoi(m[1]); err == nil {
+			length = n
+		}
+	}
+	return func(value interface{}) (interface{}, error) {
+		masked, err := mask(value)
+		if err != nil {
+			return nil, err
+		}
+		switch v := masked.(type) {
+		case int64:
+			if v < 0 || v > maxInt {
+				v %= maxInt + 1
+				if v < 0 {
+					v += maxInt + 1
+				}
+			}
+			return v, nil
+		case string:
+			if r := []rune(v); length > 0 && len(r) > length {
+				return string(r[:length]), nil
+			}
+			return v, nil
+		default:
+			return masked, nil
+		}
+	}
+}
+
+// integerColumnMax is the largest value of an integer column which a masked value may take.
+func integerColumnMax(columnType string) int64 {
+	ct := strings.ToLower(columnType)
+	unsigned := strings.Contains(ct, "unsigned")
+	var max int64
+	switch {
+	case strings.HasPrefix(ct, "tinyint"):
+		max = math.MaxInt8
+	case strings.HasPrefix(ct, "smallint"):
+		max = math.MaxInt16
+	case strings.HasPrefix(ct, "mediumint"):
+		max = 1<<23 - 1
+	default:
+		return maxMaskedInt
+	}
+	if unsigned {
+		max = max*2 + 1
+	}
+	return max
+}
+
+// MaskHash replaces values with a keyed hash of them. Integers stay integers and bytes stay bytes.
+// The same key always yields the same value, so joins between masked columns keep working.
+// Integers are not negative and no larger than INT, and SetMaskingRules fits strings to their columns.
+// Different values may be masked alike, the more likely the smaller the column, like a SMALLINT.
+func MaskHash(key []byte) MaskFunc {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		sum := hmacSum(key, value)
+		switch value.(type) {
+		case int64:
+			return int64(binary.BigEndian.Uint64(sum) % (maxMaskedInt + 1)), nil
+		case []byte:
+			return sum, nil
+		default:
+			return hex.EncodeToString(sum), nil
+		}
+	}
+}
+
+// MaskEmail replaces values with an address under example.com derived from a keyed hash of them.
+func MaskEmail(key []byte) MaskFunc {
+	return func(value interface{}) (interface{}, error) {
+		if value == nil {
+			return nil, nil
+		}
+		return fmt.Sprintf("user-%s@example.com", hex.EncodeToString(hmacSum(key, value))[:16]), nil
+	}
+}
+
+// MaskNull replaces values with NULL.
+func MaskNull() MaskFunc {
+	return func(value interface{}) (interface{}, error) {
+		return nil, nil
+	}
+}
+
+// MaskTruncate cuts strings to n characters and bytes to n bytes.
+func MaskTruncate(n int) MaskFunc {
+	return func(value interface{}) (interface{}, error) {
+		switch v := value.(type) {
+		case string:
+			if r := []rune(v); len(r) > n {
+				return string(r[:n]), nil
+			}
+			return v, nil
+		case []byte:
+			if len(v) > n {
+				return v[:n], nil
+			}
+			return v, nil
+		default:
+			return value, nil
+		}
+	}
+}
+
+func hmacSum(key []byte, value interface{}) []byte {
+	mac := hmac.New(sha256.New, key)
+	b, ok := value.([]byte)
+	if !ok {
+		b = []byte(fmt.Sprintf("%v", value))
+	}
+	mac.Write(b)
+	return mac.Sum(nil)
+}
+
+// maskValue applies mask to a scanned value and keeps sql.Null* values wrapped.
+func maskValue(mask MaskFunc, col *driver.Column, value interface{}) (interface{}, error) {
+	raw, err := sqlValue(value)
+	if err != nil {
+		return nil, err
+	}
+	masked, err := mask(raw)
+	if err != nil {
+		return nil, fmt.Errorf("masking %s: %v", col.Name, err)
+	}
+
+	var scanner sql.Scanner
+	switch value.(type) {
+	case sql.NullInt64:
+		scanner = &sql.NullInt64{}
+	case sql.NullFloat64:
+		scanner = &sql.NullFloat64{}
+	case sql.NullBool:
+		scanner = &sql.NullBool{}
+	case sql.NullString:
+		scanner = &sql.NullString{}
+	case gomysql.NullTime:
+		scanner = &gomysql.NullTime{}
+	default:
+		return masked, nil
+	}
+	if err := scanner.Scan(masked); err != nil {
+		return nil, fmt.Errorf("masking %s: %v", col.Name, err)
+	}
+	return reflect.ValueOf(scanner).Elem().Interface(), nil
+}
+
+func maskRows(masks map[string]MaskFunc, rows []*driver.Row) error {
+	if len(masks) == 0 {
+		return nil
+	}
+	for _, row := range rows {
+		for name, cv := range row.Values {
+			mask, ok := masks[name]
+			if !ok {
+				continue
+			}
+			v, err := maskValue(mask, cv.Column, cv.Value)
+			if err != nil {
+				return err
+			}
+			cv.Value = v
+		}
+	}
+	return nil
+}
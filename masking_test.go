@@ -0,0 +1,112 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MaskHash(t *testing.T) {
+	mask := MaskHash([]byte("secret"))
+
+	a, err := mask(int64(1))
+	assert.NoError(t, err)
+	b, err := mask(int64(1))
+	assert.NoError(t, err)
+	assert.IsType(t, int64(0), a)
+	assert.Equal(t, a, b)
+	assert.NotEqual(t, int64(1), a)
+	assert.True(t, a.(int64) >= 0 && a.(int64) <= math.MaxInt32, "fits INT")
+
+	s, err := mask("alice")
+	assert.NoError(t, err)
+	assert.Len(t, s, 64)
+
+	n, err := mask(nil)
+	assert.NoError(t, err)
+	assert.Nil(t, n)
+}
+
+func Test_MaskValue(t *testing.T) {
+	col := driver.NewColumn("email", 0, driver.ColumnTypeString, false, false)
+
+	v, err := maskValue(MaskTruncate(3), col, sql.NullString{String: "alice", Valid: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, sql.NullString{String: "ali", Valid: true}, v)
+	}
+
+	v, err = maskValue(MaskNull(), col, sql.NullString{String: "alice", Valid: true})
+	if assert.NoError(t, err) {
+		assert.Equal(t, sql.NullString{}, v)
+	}
+
+	v, err = maskValue(MaskEmail([]byte("secret")), col, "alice@example.org")
+	if assert.NoError(t, err) {
+		assert.Regexp(t, `^user-[0-9a-f]{16}@example\.com$`, v)
+	}
+}
+
+func Test_FitMask(t *testing.T) {
+	mask := fitMask(MaskHash([]byte("secret")), "varchar(8)")
+	s, err := mask("alice")
+	if assert.NoError(t, err) {
+		assert.Len(t, s, 8)
+	}
+
+	for columnType, max := range map[string]int64{"smallint(6)": math.MaxInt16, "tinyint(3) unsigned": math.MaxUint8, "bigint(20)": math.MaxInt32} {
+		overflow := fitMask(func(interface{}) (interface{}, error) { return int64(math.MaxInt64), nil }, columnType)
+		v, err := overflow(int64(1))
+		if assert.NoError(t, err) {
+			assert.True(t, v.(int64) >= 0 && v.(int64) <= max, columnType)
+		}
+	}
+
+	text := fitMask(MaskEmail([]byte("secret")), "text")
+	e, err := text("alice@example.org")
+	if assert.NoError(t, err) {
+		assert.Regexp(t, `^user-[0-9a-f]{16}@example\.com$`, e)
+	}
+}
+
+func Test_SetMaskingRules(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+
+	// Open connection
+	conn, err := Open(ctx, dsn, nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	key := []byte("secret")
+	assert.NoError(t, conn.SetMaskingRules(ctx, MaskingRules{tableName: {"name": MaskHash(key)}}))
+	// masked keys may collide
+	assert.Error(t, conn.SetMaskingRules(ctx, MaskingRules{tableName: {"id": MaskHash(key)}}))
+	assert.Error(t, conn.SetMaskingRules(ctx, MaskingRules{tableName: {"email": MaskHash(key)}}))
+}
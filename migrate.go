@@ -0,0 +1,355 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+const (
+	defaultMigrationsTableName  = "schema_migrations"
+	defaultMigrationLockTimeout = 30 * time.Second
+)
+
+// Migration is one version of the schema. Its steps may be edited only before it is applied,
+// as the history keeps their checksum.
+type Migration struct {
+	// Version orders migrations, and is unique and positive.
+	Version int64
+	Name    string
+	Up      *MigrationStep
+	// Down reverts Up. Without it, the migration cannot be reverted.
+	Down *MigrationStep
+}
+
+// MigrationStep is a change of the schema. New tables of Schemas are created and existing ones are
+// changed to them, online under SchemaChangeOnline and by ALTER TABLE otherwise. SQL runs afterwards.
+type MigrationStep struct {
+	Schemas []*driver.Schema
+	SQL     []string
+}
+
+// AppliedMigration is a migration in the history table.
+type AppliedMigration struct {
+	Version   int64
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+// MigrateOptions control the migration runner.
+type MigrateOptions struct {
+	// TableName is the history table. It defaults to schema_migrations.
+	TableName string
+	// LockTimeout limits the wait for another runner, which holds the advisory lock
+	// of the history table. It defaults to 30s.
+	LockTimeout time.Duration
+}
+
+func (opts *MigrateOptions) withDefaults() *MigrateOptions {
+	o := MigrateOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.TableName == "" {
+		o.TableName = defaultMigrationsTableName
+	}
+	if o.LockTimeout <= 0 {
+		o.LockTimeout = defaultMigrationLockTimeout
+	}
+	return &o
+}
+
+// MigrationChecksumError is returned when an applied migration was edited since.
+type MigrationChecksumError struct {
+	Version int64
+	Name    string
+}
+
+func (e *MigrationChecksumError) Error() string {
+	return fmt.Sprintf("migration %d %s was edited after it was applied", e.Version, e.Name)
+}
+
+// Checksum identifies the steps of the migration.
+func (m *Migration) Checksum() string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %s\n", m.Version, m.Name)
+	for _, step := range []*MigrationStep{m.Up, m.Down} {
+		if step == nil {
+			fmt.Fprint(h, "-\n")
+			continue
+		}
+		for _, sc := range step.Schemas {
+			fmt.Fprintf(h, "schema %s %s\n", sc.Name, schemaChecksum(&liveTable{schema: sc}))
+		}
+		for _, q := range step.SQL {
+			fmt.Fprintf(h, "sql %q\n", q)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// sortMigrations sorts migrations by version and checks the versions.
+func sortMigrations(migrations []*Migration) ([]*Migration, error) {
+	sorted := make([]*Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	for i, m := range sorted {
+		if m.Version <= 0 {
+			return nil, fmt.Errorf("version of migration %s must be positive", m.Name)
+		}
+		if i > 0 && sorted[i-1].Version == m.Version {
+			return nil, fmt.Errorf("version %d of migrations %s and %s must be unique", m.Version, sorted[i-1].Name, m.Name)
+		}
+	}
+	return sorted, nil
+}
+
+// checkHistory checks that every applied migration is one of migrations and unchanged.
+func checkHistory(migrations []*Migration, history []*AppliedMigration) (map[int64]*Migration, error) {
+	byVersion := make(map[int64]*Migration, len(migrations))
+	for _, m := range migrations {
+		byVersion[m.Version] = m
+	}
+	for _, am := range history {
+		m, ok := byVersion[am.Version]
+		if !ok {
+			return nil, fmt.Errorf("applied migration %d %s is unknown", am.Version, am.Name)
+		}
+		if m.Checksum() != am.Checksum {
+			return nil, &MigrationChecksumError{Version: m.Version, Name: m.Name}
+		}
+	}
+	return byVersion, nil
+}
+
+// pendingMigrations lists the migrations to apply, which have to come after the applied ones.
+func pendingMigrations(migrations []*Migration, history []*AppliedMigration) ([]*Migration, error) {
+	sorted, err := sortMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := checkHistory(sorted, history); err != nil {
+		return nil, err
+	}
+	var latest int64
+	applied := make(map[int64]bool, len(history))
+	for _, am := range history {
+		applied[am.Version] = true
+		if am.Version > latest {
+			latest = am.Version
+		}
+	}
+	var pending []*Migration
+	for _, m := range sorted {
+		if applied[m.Version] {
+			continue
+		}
+		if m.Version < latest {
+			return nil, fmt.Errorf("migration %d %s comes before the applied migration %d", m.Version, m.Name, latest)
+		}
+		pending = append(pending, m)
+	}
+	return pending, nil
+}
+
+// revertedMigrations lists the latest steps applied migrations, latest first.
+func revertedMigrations(migrations []*Migration, history []*AppliedMigration, steps int) ([]*Migration, error) {
+	if _, err := sortMigrations(migrations); err != nil {
+		return nil, err
+	}
+	byVersion, err := checkHistory(migrations, history)
+	if err != nil {
+		return nil, err
+	}
+	var reverted []*Migration
+	for i := len(history) - 1; i >= 0 && len(reverted) < steps; i-- {
+		m := byVersion[history[i].Version]
+		if m.Down == nil {
+			return nil, fmt.Errorf("migration %d %s cannot be reverted", m.Version, m.Name)
+		}
+		reverted = append(reverted, m)
+	}
+	return reverted, nil
+}
+
+// MigrationHistory lists the applied migrations by version.
+func (c *Conn) MigrationHistory(ctx context.Context, opts *MigrateOptions) ([]*AppliedMigration, error) {
+	opts = opts.withDefaults()
+	exists, err := tableExistsDB(ctx, c.db, opts.TableName)
+	if err != nil || !exists {
+		return nil, err
+	}
+	return c.migrationHistory(ctx, opts.TableName)
+}
+
+func (c *Conn) migrationHistory(ctx context.Context, tableName string) ([]*AppliedMigration, error) {
+	rows, err := selectMigrationsDB(ctx, c.db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var history []*AppliedMigration
+	for rows.Next() {
+		am := &AppliedMigration{}
+		var appliedAt int64
+		if err := rows.Scan(&am.Version, &am.Name, &am.Checksum, &appliedAt); err != nil {
+			return nil, err
+		}
+		am.AppliedAt = time.Unix(appliedAt, 0)
+		history = append(history, am)
+	}
+	return history, rows.Err()
+}
+
+// MigrateUp applies the migrations which are not in the history yet, in order of version, and returns them.
+// A migration failing stops the run, and the ones applied before it are returned.
+func (c *Conn) MigrateUp(ctx context.Context, migrations []*Migration, opts *MigrateOptions) ([]*Migration, error) {
+	if err := c.checkWrite("MigrateUp"); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
+	var applied []*Migration
+	err := c.withMigrationLock(ctx, opts, func(history []*AppliedMigration) error {
+		pending, err := pendingMigrations(migrations, history)
+		if err != nil {
+			return err
+		}
+		for _, m := range pending {
+			if err := c.applyMigrationStep(ctx, "MigrateUp", m.Up); err != nil {
+				return fmt.Errorf("migration %d %s: %v", m.Version, m.Name, err)
+			}
+			if err := insertMigrationDB(ctx, c.db, opts.TableName, m.Version, m.Name, m.Checksum()); err != nil {
+				return err
+			}
+			applied = append(applied, m)
+		}
+		return nil
+	})
+	return applied, err
+}
+
+// MigrateDown reverts the latest steps applied migrations, latest first, and returns them.
+func (c *Conn) MigrateDown(ctx context.Context, migrations []*Migration, steps int, opts *MigrateOptions) ([]*Migration, error) {
+	if err := c.checkWrite("MigrateDown"); err != nil {
+		return nil, err
+	}
+	opts = opts.withDefaults()
+
+	var reverted []*Migration
+	err := c.withMigrationLock(ctx, opts, func(history []*AppliedMigration) error {
+		ms, err := revertedMigrations(migrations, history, steps)
+		if err != nil {
+			return err
+		}
+		for _, m := range ms {
+			if err := c.applyMigrationStep(ctx, "MigrateDown", m.Down); err != nil {
+				return fmt.Errorf("migration %d %s: %v", m.Version, m.Name, err)
+			}
+			if err := deleteMigrationDB(ctx, c.db, opts.TableName, m.Version); err != nil {
+				return err
+			}
+			reverted = append(reverted, m)
+		}
+		return nil
+	})
+	return reverted, err
+}
+
+// withMigrationLock runs fn on the history while holding the advisory lock of the history table,
+// so that concurrent runners apply each migration once.
+func (c *Conn) withMigrationLock(ctx context.Context, opts *MigrateOptions, fn func(history []*AppliedMigration) error) (err error) {
+	database, err := c.databaseName(ctx)
+	if err != nil {
+		return err
+	}
+	lock, err := c.acquireLock(ctx, opts.LockTimeout, lockName(database, opts.TableName))
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if rerr := lock.release(); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	if err := createMigrationsTableDB(ctx, c.db, opts.TableName); err != nil {
+		return err
+	}
+	history, err := c.migrationHistory(ctx, opts.TableName)
+	if err != nil {
+		return err
+	}
+	return fn(history)
+}
+
+func (c *Conn) applyMigrationStep(ctx context.Context, operation string, step *MigrationStep) error {
+	if step == nil {
+		return nil
+	}
+	for _, sc := range step.Schemas {
+		// the table is locked as for any other write, so that writers honouring the lock wait for the DDL
+		err := c.withWriteLock(ctx, []string{sc.Name}, func(ctx context.Context) error {
+			return c.applyMigrationSchema(ctx, operation, sc)
+		})
+		if err != nil {
+			return err
+		}
+	}
+	for _, q := range step.SQL {
+		if _, err := c.db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// applyMigrationSchema creates a table or changes it to sc, keeping its rows.
+// Dropping or truncating columns asks ConfirmDrop for the table.
+func (c *Conn) applyMigrationSchema(ctx context.Context, operation string, sc *driver.Schema) error {
+	exists, err := tableExistsDB(ctx, c.db, sc.Name)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return createTableDB(ctx, c.db, sc)
+	}
+
+	var opts *AlterOptions
+	if c.opts != nil {
+		opts = c.opts.Alter
+	}
+	plan, err := c.PlanAlter(ctx, sc.Name, sc, opts)
+	if err != nil {
+		return err
+	}
+	if plan.losesData() {
+		if err := c.checkDrop(ctx, operation, sc.Name); err != nil {
+			return err
+		}
+	}
+	if err := opts.confirmRenames(ctx, plan); err != nil {
+		return err
+	}
+	if c.opts != nil && c.opts.SchemaChange == SchemaChangeOnline {
+		// the shadow table is filled by column name, so a renamed column would lose its values,
+		// and it keeps the indexes of the table
+		for _, a := range plan.Alterations {
+			switch a.Kind {
+			case AlterRenameColumn:
+				return fmt.Errorf("column %s of %s cannot be renamed in an online schema change", a.Previous.Name, sc.Name)
+			case AlterAddIndex, AlterDropIndex:
+				return fmt.Errorf("indexes of %s cannot change in an online schema change", sc.Name)
+			}
+		}
+		return c.changeSchemaOnline(ctx, sc.Name, sc, c.opts.OnlineSchemaChange)
+	}
+	return c.applyAlterPlan(ctx, plan, opts)
+}
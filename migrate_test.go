@@ -0,0 +1,118 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PendingMigrations(t *testing.T) {
+	first := &Migration{Version: 1, Name: "create", Up: &MigrationStep{SQL: []string{"CREATE TABLE a (id INT)"}}, Down: &MigrationStep{SQL: []string{"DROP TABLE a"}}}
+	second := &Migration{Version: 2, Name: "insert", Up: &MigrationStep{SQL: []string{"INSERT INTO a VALUES (1)"}}}
+	third := &Migration{Version: 3, Name: "delete", Up: &MigrationStep{SQL: []string{"DELETE FROM a"}}, Down: &MigrationStep{}}
+	migrations := []*Migration{third, first, second}
+	history := []*AppliedMigration{
+		{Version: 1, Name: "create", Checksum: first.Checksum()},
+		{Version: 2, Name: "insert", Checksum: second.Checksum()},
+	}
+
+	pending, err := pendingMigrations(migrations, history)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*Migration{third}, pending)
+	}
+	pending, err = pendingMigrations(migrations, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*Migration{first, second, third}, pending)
+	}
+
+	_, err = pendingMigrations([]*Migration{first, third}, history)
+	assert.Error(t, err)
+	_, err = pendingMigrations(migrations, history[1:])
+	assert.Error(t, err)
+	_, err = pendingMigrations([]*Migration{first, {Version: 1, Name: "again"}}, nil)
+	assert.Error(t, err)
+
+	edited := *second
+	edited.Up = &MigrationStep{SQL: []string{"INSERT INTO a VALUES (2)"}}
+	_, err = pendingMigrations([]*Migration{first, &edited, third}, history)
+	assert.Equal(t, &MigrationChecksumError{Version: 2, Name: "insert"}, err)
+
+	// the second migration has no down step
+	_, err = revertedMigrations(migrations, history, 2)
+	assert.Error(t, err)
+	history = append(history, &AppliedMigration{Version: 3, Name: "delete", Checksum: third.Checksum()})
+	reverted, err := revertedMigrations(migrations, history, 1)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*Migration{third}, reverted)
+	}
+}
+
+func Test_Migrate(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	create := &Migration{
+		Version: 1,
+		Name:    "create example",
+		Up: &MigrationStep{Schemas: []*driver.Schema{{
+			Name:       tableName,
+			PrimaryKey: pk,
+			Columns:    []*driver.Column{driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false)},
+		}}},
+		Down: &MigrationStep{SQL: []string{"DROP TABLE `example`"}},
+	}
+	addName := &Migration{
+		Version: 2,
+		Name:    "add name",
+		Up: &MigrationStep{Schemas: []*driver.Schema{{
+			Name:       tableName,
+			PrimaryKey: pk,
+			Columns: []*driver.Column{
+				driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+				driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			},
+		}}},
+		Down: &MigrationStep{SQL: []string{"ALTER TABLE `example` DROP COLUMN `name`"}},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := conn.(*Conn)
+
+	// Applying all migrations, then none
+	applied, err := c.MigrateUp(ctx, []*Migration{create, addName}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 2)
+	applied, err = c.MigrateUp(ctx, []*Migration{create, addName}, nil)
+	assert.NoError(t, err)
+	assert.Len(t, applied, 0)
+	sc, err := c.GetSchema(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, sc.Columns, 2)
+	}
+
+	// Reverting the latest migration
+	reverted, err := c.MigrateDown(ctx, []*Migration{create, addName}, 1, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, []*Migration{addName}, reverted)
+	history, err := c.MigrationHistory(ctx, nil)
+	if assert.NoError(t, err) && assert.Len(t, history, 1) {
+		assert.Equal(t, int64(1), history[0].Version)
+		assert.Equal(t, create.Checksum(), history[0].Checksum)
+	}
+}
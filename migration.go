@@ -0,0 +1,281 @@
+package mysql
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// ChangeRisk classifies a schema change for review.
+type ChangeRisk int
+
+const (
+	// ChangeSafe neither blocks writes for long nor loses data.
+	ChangeSafe ChangeRisk = iota
+	// ChangeLocking copies or rebuilds the table while blocking writes.
+	ChangeLocking
+	// ChangeDataLosing drops or may truncate values.
+	ChangeDataLosing
+)
+
+func (r ChangeRisk) String() string {
+	switch r {
+	case ChangeSafe:
+		return "safe"
+	case ChangeLocking:
+		return "locking"
+	case ChangeDataLosing:
+		return "data-losing"
+	default:
+		return fmt.Sprintf("<unknown risk: %d>", r)
+	}
+}
+
+func (r ChangeRisk) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *ChangeRisk) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	for _, risk := range []ChangeRisk{ChangeSafe, ChangeLocking, ChangeDataLosing} {
+		if risk.String() == s {
+			*r = risk
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown risk: %s", s)
+}
+
+// MigrationChange is one change of a migration plan.
+type MigrationChange struct {
+	Description string         `json:"description"`
+	Algorithm   AlterAlgorithm `json:"algorithm"`
+	Risk        ChangeRisk     `json:"risk"`
+}
+
+// MigrationPlan lists the changes between a live table and a desired schema, and the statement applying them.
+// Secondary indexes are planned from AlterOptions.Indexes, as driver.Schema has none.
+// A plan is applied as it is, so its JSON can be reviewed, stored and applied later.
+type MigrationPlan struct {
+	TableName string             `json:"table_name"`
+	Changes   []*MigrationChange `json:"changes"`
+	Risk      ChangeRisk         `json:"risk"`
+	Algorithm AlterAlgorithm     `json:"algorithm"`
+	Statement string             `json:"statement"`
+	// SchemaChecksum identifies the live schema the plan was made for.
+	SchemaChecksum string `json:"schema_checksum"`
+	// Checksum covers the rest of the plan, so that a plan edited after its review is refused.
+	Checksum string `json:"checksum"`
+}
+
+// MigrationPlanOutdatedError refuses a plan made for a table which has changed since.
+type MigrationPlanOutdatedError struct {
+	TableName string
+}
+
+func (e *MigrationPlanOutdatedError) Error() string {
+	return fmt.Sprintf("schema of %s changed since the migration plan was made", e.TableName)
+}
+
+// MigrationPlanChecksumError refuses a plan which does not match its checksum.
+type MigrationPlanChecksumError struct {
+	TableName string
+}
+
+func (e *MigrationPlanChecksumError) Error() string {
+	return fmt.Sprintf("migration plan of %s does not match its checksum", e.TableName)
+}
+
+// classifyAlteration rates an alteration run by algorithm.
+func classifyAlteration(a *Alteration, algorithm AlterAlgorithm) ChangeRisk {
+	switch a.Kind {
+	case AlterDropColumn:
+		return ChangeDataLosing
+	case AlterModifyColumn, AlterRenameColumn:
+		// values of another type may be truncated, and NULLs do not fit a NOT NULL column
+		if a.Column.Type != a.Previous.Type || (a.Column.NotNull && !a.Previous.NotNull) {
+			return ChangeDataLosing
+		}
+	case AlterChangePrimaryKey:
+		return ChangeLocking
+	case AlterAddIndex:
+		// FULLTEXT and SPATIAL indexes are built without concurrent writes
+		if a.Index.special() {
+			return ChangeLocking
+		}
+	}
+	if algorithm == AlterAlgorithmCopy {
+		return ChangeLocking
+	}
+	return ChangeSafe
+}
+
+func describeAlteration(a *Alteration) string {
+	switch a.Kind {
+	case AlterAddColumn:
+		def, err := generateColumnDefinition(a.Column)
+		if err != nil {
+			return a.String()
+		}
+		return fmt.Sprintf("%s %s", a.Kind, def)
+	case AlterAddIndex:
+		return fmt.Sprintf("%s %s", a.Kind, generateIndexDefinition(a.Index))
+	case AlterModifyColumn, AlterRenameColumn:
+		if a.Kind == AlterRenameColumn && sameDefinition(a.Column, a.Previous) {
+			return a.String()
+		}
+		def, err := a.definition()
+		if err != nil {
+			return a.String()
+		}
+		return fmt.Sprintf("%s %s (was %s)", a.Kind, def, generateLiveColumnDefinition(a.Previous, a.Definition))
+	default:
+		return a.String()
+	}
+}
+
+// schemaChecksum identifies the live columns, primary key and secondary indexes of a table, so that
+// a change of a column type, default, character set or comment outdates the plans made before it.
+func schemaChecksum(t *liveTable) string {
+	var b strings.Builder
+	for _, col := range t.schema.Columns {
+		fmt.Fprintf(&b, "%s %d %s %t %t", col.Name, col.OrdinalPosition, col.Type, col.NotNull, col.AutoIncrement)
+		if def := t.defs[col.Name]; def != nil {
+			fmt.Fprintf(&b, " %q %q %q %q %q %q %t", def.ColumnType, def.Default, def.OnUpdate, def.CharacterSet, def.Collation, def.Comment, def.Generated)
+		}
+		b.WriteString("\n")
+	}
+	fmt.Fprintf(&b, "PRIMARY KEY %s\n", strings.Join(primaryKeyNames(t.schema), ","))
+	for _, index := range t.indexes {
+		fmt.Fprintf(&b, "INDEX %s %t %s", index.Name, index.Unique, index.Type)
+		for _, col := range index.Columns {
+			fmt.Fprintf(&b, " %s(%d)", col.Name, col.Length)
+		}
+		b.WriteString("\n")
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+// checksum identifies the contents of the plan.
+func (mp *MigrationPlan) checksum() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n%s %s %s\n", mp.TableName, mp.Risk, mp.Algorithm, mp.SchemaChecksum)
+	for _, change := range mp.Changes {
+		fmt.Fprintf(&b, "%q %s %s\n", change.Description, change.Algorithm, change.Risk)
+	}
+	b.WriteString(mp.Statement)
+	sum := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(sum[:])
+}
+
+func (mp *MigrationPlan) verify() error {
+	if mp.Checksum != mp.checksum() {
+		return &MigrationPlanChecksumError{TableName: mp.TableName}
+	}
+	return nil
+}
+
+func newMigrationPlan(current *liveTable, plan *AlterPlan, opts *AlterOptions) (*MigrationPlan, error) {
+	mp := &MigrationPlan{
+		TableName:      plan.TableName,
+		Algorithm:      plan.Algorithm,
+		SchemaChecksum: schemaChecksum(current),
+	}
+	for i, a := range plan.Alterations {
+		change := &MigrationChange{
+			Description: describeAlteration(a),
+			Algorithm:   plan.Algorithms[i],
+			Risk:        classifyAlteration(a, plan.Algorithms[i]),
+		}
+		if change.Risk > mp.Risk {
+			mp.Risk = change.Risk
+		}
+		mp.Changes = append(mp.Changes, change)
+	}
+	if len(plan.Alterations) > 0 {
+		algorithm, lock, err := opts.statementOptions(plan)
+		if err != nil {
+			return nil, err
+		}
+		if mp.Statement, err = generateAlterTableQuery(plan.TableName, plan.Alterations, algorithm, lock); err != nil {
+			return nil, err
+		}
+	}
+	mp.Checksum = mp.checksum()
+	return mp, nil
+}
+
+// PlanMigration plans the migration of a live table to sc for review. opts decide the ALGORITHM and LOCK
+// of the statement and the renamed columns as in AlterTable. Detected renames are listed among the changes,
+// and are confirmed by the review of the plan rather than by ConfirmRenames.
+func (c *Conn) PlanMigration(ctx context.Context, tableName string, sc *driver.Schema, opts *AlterOptions) (*MigrationPlan, error) {
+	current, plan, err := c.planAlter(ctx, tableName, sc, opts)
+	if err != nil {
+		return nil, err
+	}
+	return newMigrationPlan(current, plan, opts)
+}
+
+// ApplyMigrationPlan runs the statement of a reviewed plan, unless the plan was edited or the table
+// changed since the plan was made. A data-losing plan asks ConfirmDrop for the table.
+func (c *Conn) ApplyMigrationPlan(ctx context.Context, mp *MigrationPlan) error {
+	if err := c.checkWrite("ApplyMigrationPlan"); err != nil {
+		return err
+	}
+	if err := mp.verify(); err != nil {
+		return err
+	}
+	if mp.Risk == ChangeDataLosing {
+		if err := c.checkDrop(ctx, "ApplyMigrationPlan", mp.TableName); err != nil {
+			return err
+		}
+	}
+	return c.withWriteLock(ctx, []string{mp.TableName}, func(ctx context.Context) error {
+		return c.applyMigrationPlan(ctx, mp)
+	})
+}
+
+func (c *Conn) applyMigrationPlan(ctx context.Context, mp *MigrationPlan) error {
+	v, err := c.serverVersion(ctx)
+	if err != nil {
+		return err
+	}
+	current, err := c.getLiveTable(ctx, v, mp.TableName)
+	if err != nil {
+		return err
+	}
+	if schemaChecksum(current) != mp.SchemaChecksum {
+		return &MigrationPlanOutdatedError{TableName: mp.TableName}
+	}
+	if mp.Statement == "" {
+		return nil
+	}
+	if _, err := c.db.ExecContext(ctx, mp.Statement); err != nil {
+		return err
+	}
+	return nil
+}
+
+// String renders the plan as text for review.
+func (mp *MigrationPlan) String() string {
+	var b strings.Builder
+	if len(mp.Changes) == 0 {
+		fmt.Fprintf(&b, "Migration of %s: no changes\n", mp.TableName)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Migration of %s: %s\n", mp.TableName, mp.Risk)
+	for _, change := range mp.Changes {
+		fmt.Fprintf(&b, "  [%s] %s (%s)\n", change.Risk, change.Description, change.Algorithm)
+	}
+	fmt.Fprintf(&b, "Statement:\n  %s\n", mp.Statement)
+	return b.String()
+}
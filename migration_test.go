@@ -0,0 +1,85 @@
+package mysql
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MigrationPlan(t *testing.T) {
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	current := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("age", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+	sc := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, false, false),
+			driver.NewColumn("age", 2, driver.ColumnTypeInt, false, false),
+			driver.NewColumn("email", 3, driver.ColumnTypeString, false, false),
+		},
+	}
+
+	defs := map[string]*ColumnDefinition{
+		"id":   {ColumnType: "int"},
+		"name": {ColumnType: "varchar(64)"},
+		"age":  {ColumnType: "varchar(8)"},
+	}
+	live := &liveTable{schema: current, defs: defs}
+	mysql8 := &ServerVersion{Major: 8, Minor: 0, Patch: 30}
+	alterations := diffSchema(current, sc, nil)
+	setColumnDefinitions(alterations, defs)
+	plan := newAlterPlan(mysql8, "user", current, sc, alterations)
+	_, err := newMigrationPlan(live, plan, nil)
+	assert.IsType(t, &AlterCopyError{}, err)
+
+	mp, err := newMigrationPlan(live, plan, &AlterOptions{Copy: AlterCopyAllow})
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.Len(t, mp.Changes, 2) {
+		assert.Equal(t, ChangeDataLosing, mp.Changes[0].Risk)
+		assert.Equal(t, AlterAlgorithmCopy, mp.Changes[0].Algorithm)
+		assert.Equal(t, ChangeSafe, mp.Changes[1].Risk)
+	}
+	assert.Equal(t, ChangeDataLosing, mp.Risk)
+	assert.Equal(t, schemaChecksum(live), mp.SchemaChecksum)
+	assert.NotEqual(t, schemaChecksum(&liveTable{schema: sc, defs: defs}), mp.SchemaChecksum)
+	// a live change the generic schema does not tell outdates the plan as well
+	widened := map[string]*ColumnDefinition{"id": defs["id"], "name": {ColumnType: "varchar(255)"}, "age": defs["age"]}
+	assert.NotEqual(t, schemaChecksum(&liveTable{schema: current, defs: widened}), mp.SchemaChecksum)
+	indexed := &liveTable{schema: current, defs: defs, indexes: []*Index{{Name: "idx_name", Columns: []IndexColumn{{Name: "name"}}}}}
+	assert.NotEqual(t, schemaChecksum(indexed), mp.SchemaChecksum)
+	assert.Contains(t, mp.String(), "[data-losing] modify column `age` INT NULL (was `age` varchar(8) NULL) (COPY)")
+
+	b, err := json.Marshal(mp)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Contains(t, string(b), `"risk":"data-losing"`)
+	reviewed := &MigrationPlan{}
+	if assert.NoError(t, json.Unmarshal(b, reviewed)) {
+		assert.Equal(t, mp, reviewed)
+		assert.NoError(t, reviewed.verify())
+	}
+
+	// a statement edited after the review is refused
+	reviewed.Statement = "DROP TABLE `user`"
+	assert.Equal(t, &MigrationPlanChecksumError{TableName: "user"}, reviewed.verify())
+
+	mp, err = newMigrationPlan(live, newAlterPlan(mysql8, "user", current, current, nil), nil)
+	if assert.NoError(t, err) {
+		assert.Empty(t, mp.Statement)
+		assert.Equal(t, "Migration of user: no changes\n", mp.String())
+	}
+}
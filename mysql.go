@@ -1,12 +1,14 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"reflect"
 	"strings"
 	"time"
 
+	gomysql "github.com/go-sql-driver/mysql"
 	"github.com/go-tamate/tamate/driver"
 )
 
@@ -91,7 +93,7 @@ func colToMySQLType(c *driver.Column) reflect.Type {
 		if c.NotNull {
 			return reflect.TypeOf(time.Time{})
 		}
-		return reflect.TypeOf(nil)
+		return reflect.TypeOf(gomysql.NullTime{})
 	case driver.ColumnTypeString:
 		if c.NotNull {
 			return reflect.TypeOf("")
@@ -107,6 +109,13 @@ func colToMySQLType(c *driver.Column) reflect.Type {
 // Exec Query
 //------------
 
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
 func exec(user, password, dbName, query string) (sql.Result, error) {
 	dsn := fmt.Sprintf("%s:%s@/%s", user, password, dbName)
 	db, err := sql.Open(driverName, dsn)
@@ -146,15 +155,15 @@ func createTable(user, password, dbName string, sc *driver.Schema) error {
 		return err
 	}
 	defer db.Close()
-	return createTableDB(db, sc)
+	return createTableDB(context.Background(), db, sc)
 }
 
-func createTableDB(db *sql.DB, sc *driver.Schema) error {
+func createTableDB(ctx context.Context, db queryer, sc *driver.Schema) error {
 	q, err := generateCreateTableQuery(sc)
 	if err != nil {
 		return err
 	}
-	if _, err := db.Exec(q); err != nil {
+	if _, err := db.ExecContext(ctx, q); err != nil {
 		return err
 	}
 	return nil
@@ -167,15 +176,15 @@ func dropTable(user, password, dbName, tableName string) error {
 		return err
 	}
 	defer db.Close()
-	return dropTableDB(db, tableName)
+	return dropTableDB(context.Background(), db, tableName)
 }
 
-func dropTableDB(db *sql.DB, tableName string) error {
+func dropTableDB(ctx context.Context, db queryer, tableName string) error {
 	q, err := generateDropTableQuery(tableName)
 	if err != nil {
 		return err
 	}
-	if _, err := db.Exec(q); err != nil {
+	if _, err := db.ExecContext(ctx, q); err != nil {
 		return err
 	}
 	return nil
@@ -188,15 +197,15 @@ func getInformationSchema(user, password, dbName, tableName string) (*sql.Rows,
 		return nil, err
 	}
 	defer db.Close()
-	return getInfomationSchemaDB(db, tableName)
+	return getInfomationSchemaDB(context.Background(), db, tableName)
 }
 
-func getInfomationSchemaDB(db *sql.DB, tableName string) (*sql.Rows, error) {
+func getInfomationSchemaDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
 	q, err := generateGetInformationSchemaQuery(tableName)
 	if err != nil {
 		return nil, err
 	}
-	return db.Query(q)
+	return db.QueryContext(ctx, q)
 }
 
 func selectRows(user, password, dbName, tableName string) (*sql.Rows, error) {
@@ -206,15 +215,15 @@ func selectRows(user, password, dbName, tableName string) (*sql.Rows, error) {
 		return nil, err
 	}
 	defer db.Close()
-	return selectRowsDB(db, tableName)
+	return selectRowsDB(context.Background(), db, tableName)
 }
 
-func selectRowsDB(db *sql.DB, tableName string) (*sql.Rows, error) {
+func selectRowsDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
 	q, err := generateSelectRowsQuery(tableName)
 	if err != nil {
 		return nil, err
 	}
-	return db.Query(q)
+	return db.QueryContext(ctx, q)
 }
 
 func insertRow(user, password, dbName, tableName string, row *driver.Row) (sql.Result, error) {
@@ -224,15 +233,15 @@ func insertRow(user, password, dbName, tableName string, row *driver.Row) (sql.R
 		return nil, err
 	}
 	defer db.Close()
-	return insertRowDB(db, tableName, row)
+	return insertRowDB(context.Background(), db, tableName, row)
 }
 
-func insertRowDB(db *sql.DB, tableName string, row *driver.Row) (sql.Result, error) {
+func insertRowDB(ctx context.Context, db queryer, tableName string, row *driver.Row) (sql.Result, error) {
 	q, err := generateInsertRowQuery(tableName, row)
 	if err != nil {
 		return nil, err
 	}
-	stmt, err := db.Prepare(q)
+	stmt, err := db.PrepareContext(ctx, q)
 	if err != nil {
 		return nil, err
 	}
@@ -242,5 +251,499 @@ func insertRowDB(db *sql.DB, tableName string, row *driver.Row) (sql.Result, err
 	for _, rowVal := range row.Values {
 		values[rowVal.Column.OrdinalPosition] = rowVal.Value
 	}
-	return stmt.Exec(values...)
+	return stmt.ExecContext(ctx, values...)
+}
+
+func getDatabaseInformationSchemaDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateGetDatabaseInformationSchemaQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func getForeignKeysDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateGetForeignKeysQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func addForeignKeyDB(ctx context.Context, db queryer, fk *ForeignKey) error {
+	q, err := generateAddForeignKeyQuery(fk)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func deleteRowsDB(ctx context.Context, db queryer, tableName string) error {
+	q, err := generateDeleteRowsQuery(tableName)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setForeignKeyChecksDB(ctx context.Context, db queryer, enabled bool) error {
+	q, err := generateSetForeignKeyChecksQuery(enabled)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func selectRowsWhereDB(ctx context.Context, db queryer, tableName, where string, args ...interface{}) (*sql.Rows, error) {
+	q, err := generateSelectRowsWhereQuery(tableName, where)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q, args...)
+}
+
+func selectRowsByQueryDB(ctx context.Context, db queryer, sc *driver.Schema, rq *RowsQuery) (*sql.Rows, error) {
+	q, args, err := generateSelectRowsByQuery(sc, rq)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q, args...)
+}
+
+func startSnapshotDB(ctx context.Context, db queryer) error {
+	qs, err := generateStartSnapshotQueries()
+	if err != nil {
+		return err
+	}
+	for _, q := range qs {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func showMasterStatusDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateShowMasterStatusQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func showSlaveStatusDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateShowSlaveStatusQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func execDB(ctx context.Context, db queryer, generate func() (string, error)) error {
+	q, err := generate()
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func selectRowsSinceDB(ctx context.Context, db queryer, sc *driver.Schema, column string, wm *Watermark, limit int) (*sql.Rows, error) {
+	q, err := generateSelectRowsSinceQuery(sc, column, wm != nil, limit)
+	if err != nil {
+		return nil, err
+	}
+	var args []interface{}
+	if wm != nil {
+		args = append(args, wm.Value, wm.Value)
+		args = append(args, wm.PrimaryKey...)
+	}
+	return db.QueryContext(ctx, q, args...)
+}
+
+func selectChunkBoundaryDB(ctx context.Context, db queryer, sc *driver.Schema, lower []interface{}, chunkSize int) (*sql.Rows, error) {
+	q, err := generateChunkBoundaryQuery(sc, lower != nil, chunkSize)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q, lower...)
+}
+
+func selectChunkChecksumDB(ctx context.Context, db queryer, sc *driver.Schema, chunk *Chunk) (count int64, checksum uint64, err error) {
+	q, err := generateChunkChecksumQuery(sc, chunk.Lower != nil, chunk.Upper != nil)
+	if err != nil {
+		return 0, 0, err
+	}
+	err = db.QueryRowContext(ctx, q, chunk.args()...).Scan(&count, &checksum)
+	return count, checksum, err
+}
+
+func getTableNamesDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateGetTableNamesQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func tableExistsDB(ctx context.Context, db queryer, tableName string) (bool, error) {
+	q, err := generateTableExistsQuery()
+	if err != nil {
+		return false, err
+	}
+	var count int
+	if err := db.QueryRowContext(ctx, q, tableName).Scan(&count); err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func createTableLikeDB(ctx context.Context, db queryer, tableName, copyName string) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateCreateTableLikeQuery(tableName, copyName)
+	})
+}
+
+func copyTableDB(ctx context.Context, db queryer, tableName, copyName string) error {
+	qs, err := generateCopyTableQueries(tableName, copyName)
+	if err != nil {
+		return err
+	}
+	for _, q := range qs {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func renameTablesDB(ctx context.Context, db queryer, renames [][2]string) error {
+	q, err := generateRenameTablesQuery(renames)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func createCopyTriggersDB(ctx context.Context, db queryer, tableName, copyName string, columnNames, pk []string, triggerNames [3]string) error {
+	qs, err := generateCreateCopyTriggerQueries(tableName, copyName, columnNames, pk, triggerNames)
+	if err != nil {
+		return err
+	}
+	for _, q := range qs {
+		if _, err := db.ExecContext(ctx, q); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func dropTriggerDB(ctx context.Context, db queryer, triggerName string) error {
+	q, err := generateDropTriggerQuery(triggerName)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func copyChunkDB(ctx context.Context, db queryer, tableName, copyName string, columnNames, pk []string, chunk *Chunk) error {
+	q, err := generateCopyChunkQuery(tableName, copyName, columnNames, pk, chunk.Lower != nil, chunk.Upper != nil)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q, chunk.args()...); err != nil {
+		return err
+	}
+	return nil
+}
+
+func setLockWaitTimeoutDB(ctx context.Context, db queryer, seconds int) error {
+	q, err := generateSetLockWaitTimeoutQuery(seconds)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getServerVersionDB(ctx context.Context, db queryer) (string, error) {
+	q, err := generateGetServerVersionQuery()
+	if err != nil {
+		return "", err
+	}
+	var version string
+	if err := db.QueryRowContext(ctx, q).Scan(&version); err != nil {
+		return "", err
+	}
+	return version, nil
+}
+
+func alterTableDB(ctx context.Context, db queryer, tableName string, alterations []*Alteration, algorithm AlterAlgorithm, lock AlterLock) error {
+	q, err := generateAlterTableQuery(tableName, alterations, algorithm, lock)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getLockDB(ctx context.Context, db queryer, name string, timeout time.Duration) (bool, error) {
+	q, err := generateGetLockQuery()
+	if err != nil {
+		return false, err
+	}
+	// GET_LOCK waits whole seconds, so the timeout is rounded up
+	seconds := int64((timeout + time.Second - 1) / time.Second)
+	var acquired sql.NullInt64
+	if err := db.QueryRowContext(ctx, q, name, seconds).Scan(&acquired); err != nil {
+		return false, err
+	}
+	if !acquired.Valid {
+		return false, fmt.Errorf("advisory lock %s failed", name)
+	}
+	return acquired.Int64 == 1, nil
+}
+
+func releaseLockDB(ctx context.Context, db queryer, name string) error {
+	q, err := generateReleaseLockQuery()
+	if err != nil {
+		return err
+	}
+	var released sql.NullInt64
+	if err := db.QueryRowContext(ctx, q, name).Scan(&released); err != nil {
+		return err
+	}
+	if released.Int64 != 1 {
+		return fmt.Errorf("advisory lock %s was not held", name)
+	}
+	return nil
+}
+
+func getConnectionIDDB(ctx context.Context, db queryer) (int64, error) {
+	q, err := generateGetConnectionIDQuery()
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := db.QueryRowContext(ctx, q).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func killConnectionDB(ctx context.Context, db queryer, id int64) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateKillConnectionQuery(id)
+	})
+}
+
+func getDatabaseNameDB(ctx context.Context, db queryer) (string, error) {
+	q, err := generateGetDatabaseNameQuery()
+	if err != nil {
+		return "", err
+	}
+	var name sql.NullString
+	if err := db.QueryRowContext(ctx, q).Scan(&name); err != nil {
+		return "", err
+	}
+	return name.String, nil
+}
+
+func createMigrationsTableDB(ctx context.Context, db queryer, tableName string) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateCreateMigrationsTableQuery(tableName)
+	})
+}
+
+func selectMigrationsDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
+	q, err := generateSelectMigrationsQuery(tableName)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func insertMigrationDB(ctx context.Context, db queryer, tableName string, version int64, name, checksum string) error {
+	q, err := generateInsertMigrationQuery(tableName)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q, version, name, checksum); err != nil {
+		return err
+	}
+	return nil
+}
+
+func deleteMigrationDB(ctx context.Context, db queryer, tableName string, version int64) error {
+	q, err := generateDeleteMigrationQuery(tableName)
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q, version); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getViewsDB(ctx context.Context, db queryer, viewName string) (*sql.Rows, error) {
+	q, err := generateGetViewsQuery(viewName != "")
+	if err != nil {
+		return nil, err
+	}
+	if viewName != "" {
+		return db.QueryContext(ctx, q, viewName)
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func createViewDB(ctx context.Context, db queryer, v *View) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateCreateViewQuery(v)
+	})
+}
+
+// tableTypeView is the type of views in INFORMATION_SCHEMA.TABLES.
+const tableTypeView = "VIEW"
+
+// getTableTypeDB returns the type of a table, or an empty one when it does not exist.
+func getTableTypeDB(ctx context.Context, db queryer, tableName string) (string, error) {
+	q, err := generateGetTableTypeQuery()
+	if err != nil {
+		return "", err
+	}
+	var tableType string
+	err = db.QueryRowContext(ctx, q, tableName).Scan(&tableType)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	return tableType, err
+}
+
+func getStoredProgramsDB(ctx context.Context, db queryer) (*sql.Rows, error) {
+	q, err := generateGetStoredProgramsQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func showCreateProgramDB(ctx context.Context, db queryer, programType ProgramType, name string) (*sql.Rows, error) {
+	q, err := generateShowCreateProgramQuery(programType, name)
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func getTriggerTableDB(ctx context.Context, db queryer, triggerName string) (string, error) {
+	q, err := generateGetTriggerTableQuery()
+	if err != nil {
+		return "", err
+	}
+	var tableName string
+	if err := db.QueryRowContext(ctx, q, triggerName).Scan(&tableName); err != nil {
+		return "", err
+	}
+	return tableName, nil
+}
+
+func lockTablesForWriteDB(ctx context.Context, db queryer, tableNames []string) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateLockTablesForWriteQuery(tableNames)
+	})
+}
+
+func dropProgramDB(ctx context.Context, db queryer, programType ProgramType, name string) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateDropProgramQuery(programType, name)
+	})
+}
+
+func getSQLModeDB(ctx context.Context, db queryer) (string, error) {
+	q, err := generateGetSQLModeQuery()
+	if err != nil {
+		return "", err
+	}
+	var sqlMode string
+	if err := db.QueryRowContext(ctx, q).Scan(&sqlMode); err != nil {
+		return "", err
+	}
+	return sqlMode, nil
+}
+
+func setSQLModeDB(ctx context.Context, db queryer, sqlMode string) error {
+	q, err := generateSetSQLModeQuery()
+	if err != nil {
+		return err
+	}
+	if _, err := db.ExecContext(ctx, q, sqlMode); err != nil {
+		return err
+	}
+	return nil
+}
+
+func getColumnDefinitionsDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
+	q, err := generateGetColumnDefinitionsQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q, tableName)
+}
+
+func getIndexesDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
+	q, err := generateGetIndexesQuery()
+	if err != nil {
+		return nil, err
+	}
+	return db.QueryContext(ctx, q, tableName)
+}
+
+func getPartitionsDB(ctx context.Context, db queryer, tableName string) (*sql.Rows, error) {
+	q, err := generateGetPartitionsQuery(tableName != "")
+	if err != nil {
+		return nil, err
+	}
+	if tableName != "" {
+		return db.QueryContext(ctx, q, tableName)
+	}
+	return db.QueryContext(ctx, q)
+}
+
+func createPartitionedTableDB(ctx context.Context, db queryer, sc *driver.Schema, p *Partitioning) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateCreatePartitionedTableQuery(sc, p)
+	})
+}
+
+func addPartitionsDB(ctx context.Context, db queryer, p *Partitioning, partitions []*Partition) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateAddPartitionsQuery(p, partitions)
+	})
+}
+
+func dropPartitionsDB(ctx context.Context, db queryer, tableName string, partitionNames []string) error {
+	return execDB(ctx, db, func() (string, error) {
+		return generateDropPartitionsQuery(tableName, partitionNames)
+	})
 }
@@ -0,0 +1,308 @@
+package mysql
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// DSN parameters read by this driver. Other parameters are passed to go-sql-driver,
+// which sets unknown ones as session variables, e.g. time_zone=%27%2B00:00%27.
+const (
+	paramMaxOpenConns     = "maxOpenConns"
+	paramMaxIdleConns     = "maxIdleConns"
+	paramConnMaxLifetime  = "connMaxLifetime"
+	paramMaxRetries       = "maxRetries"
+	paramRetryBackoff     = "retryBackoff"
+	paramRetryMaxBackoff  = "retryMaxBackoff"
+	paramReadOnly         = "readOnly"
+	paramBackup           = "backup"
+	paramSchemaChange     = "schemaChange"
+	paramWriteLockTimeout = "writeLockTimeout"
+	// paramMaxReplicationLag applies to the replicas of Options.ReplicaDSNs.
+	paramMaxReplicationLag = "maxReplicationLag"
+)
+
+// Options configure the connection pool and sessions of a connection.
+type Options struct {
+	// MaxOpenConns, MaxIdleConns and ConnMaxLifetime keep the defaults of database/sql when zero.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	// SessionVariables are set on every pooled connection, like sql_mode, time_zone,
+	// innodb_lock_wait_timeout or max_execution_time. Values other than numbers are quoted.
+	SessionVariables map[string]string
+
+	// TLS with a private CA and client certificates, from PEM files.
+	TLSCAFile     string
+	TLSCertFile   string
+	TLSKeyFile    string
+	TLSServerName string
+	// ServerPubKeyFile is a PEM file with the RSA public key of the server.
+	ServerPubKeyFile string
+	// PasswordProvider replaces the password of the DSN on every new connection.
+	PasswordProvider PasswordProvider
+
+	// MaxRetries is how often an operation failing with a transient error is repeated. It defaults to none.
+	MaxRetries int
+	// RetryBackoff is the wait before the first retry, doubled on every further one up to RetryMaxBackoff.
+	// They default to 100ms and 5s.
+	RetryBackoff    time.Duration
+	RetryMaxBackoff time.Duration
+
+	// ReadOnly refuses every operation writing to the database before any SQL runs.
+	ReadOnly bool
+	// ConfirmDrop is asked before a table is dropped, like by SetSchema and SetRows.
+	ConfirmDrop ConfirmDropFunc
+	// Backup backs up tables before SetSchema and SetRows drop them. It defaults to no backup.
+	Backup BackupMode
+	// SchemaChange is how SetSchema changes existing tables. It defaults to recreating them.
+	SchemaChange SchemaChangeMode
+	// OnlineSchemaChange tunes SchemaChangeOnline.
+	OnlineSchemaChange *OnlineSchemaChangeOptions
+	// Alter tunes SchemaChangeAlter.
+	Alter *AlterOptions
+	// WriteLockTimeout makes writes hold the advisory lock tamate:<database>.<table> of every table
+	// they write, so that they wait for each other. A write waiting longer for a lock fails
+	// with a LockTimeoutError. It defaults to no lock.
+	WriteLockTimeout time.Duration
+
+	// ReplicaDSNs are read replicas of the primary. Reads go to them in turn and writes to the primary.
+	// Session variables, TLS and the pool are configured the same as the primary.
+	ReplicaDSNs []string
+	// MaxReplicationLag skips replicas lagging further behind. It defaults to no limit.
+	MaxReplicationLag time.Duration
+	// ReplicaCheckInterval is how often the health of a replica is checked. It defaults to 1s.
+	ReplicaCheckInterval time.Duration
+}
+
+// Open connects to dsn like tamate.Open, with options in addition to the ones in dsn.
+func Open(ctx context.Context, dsn string, opts *Options) (*Conn, error) {
+	dsn, dsnOpts, err := parseDSNOptions(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return newMySQLConnWithOptions(dsn, dsnOpts.merge(opts))
+}
+
+// parseDSNOptions takes the parameters of this driver out of dsn.
+func parseDSNOptions(dsn string) (string, *Options, error) {
+	cfg, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		return "", nil, err
+	}
+
+	opts := &Options{}
+	for param, value := range cfg.Params {
+		switch param {
+		case paramMaxOpenConns:
+			if opts.MaxOpenConns, err = strconv.Atoi(value); err != nil {
+				return "", nil, err
+			}
+		case paramMaxIdleConns:
+			if opts.MaxIdleConns, err = strconv.Atoi(value); err != nil {
+				return "", nil, err
+			}
+		case paramConnMaxLifetime:
+			if opts.ConnMaxLifetime, err = time.ParseDuration(value); err != nil {
+				return "", nil, err
+			}
+		case paramMaxRetries:
+			if opts.MaxRetries, err = strconv.Atoi(value); err != nil {
+				return "", nil, err
+			}
+		case paramRetryBackoff:
+			if opts.RetryBackoff, err = time.ParseDuration(value); err != nil {
+				return "", nil, err
+			}
+		case paramRetryMaxBackoff:
+			if opts.RetryMaxBackoff, err = time.ParseDuration(value); err != nil {
+				return "", nil, err
+			}
+		case paramReadOnly:
+			if opts.ReadOnly, err = strconv.ParseBool(value); err != nil {
+				return "", nil, err
+			}
+		case paramBackup:
+			switch mode := BackupMode(value); mode {
+			case BackupNone, BackupCopy, BackupRename:
+				opts.Backup = mode
+			default:
+				return "", nil, fmt.Errorf("unknown backup mode: %s", value)
+			}
+		case paramSchemaChange:
+			switch mode := SchemaChangeMode(value); mode {
+			case SchemaChangeRecreate, SchemaChangeOnline, SchemaChangeAlter:
+				opts.SchemaChange = mode
+			default:
+				return "", nil, fmt.Errorf("unknown schema change mode: %s", value)
+			}
+		case paramWriteLockTimeout:
+			if opts.WriteLockTimeout, err = time.ParseDuration(value); err != nil {
+				return "", nil, err
+			}
+		case paramMaxReplicationLag:
+			if opts.MaxReplicationLag, err = time.ParseDuration(value); err != nil {
+				return "", nil, err
+			}
+		case paramTLSCA:
+			opts.TLSCAFile = value
+		case paramTLSCert:
+			opts.TLSCertFile = value
+		case paramTLSKey:
+			opts.TLSKeyFile = value
+		case paramTLSServerName:
+			opts.TLSServerName = value
+		case paramServerPubKeyFile:
+			opts.ServerPubKeyFile = value
+		default:
+			continue
+		}
+		delete(cfg.Params, param)
+	}
+	return cfg.FormatDSN(), opts, nil
+}
+
+// merge returns opts overridden by the set fields of other.
+func (opts *Options) merge(other *Options) *Options {
+	merged := *opts
+	if other == nil {
+		return &merged
+	}
+	if other.MaxOpenConns != 0 {
+		merged.MaxOpenConns = other.MaxOpenConns
+	}
+	if other.MaxIdleConns != 0 {
+		merged.MaxIdleConns = other.MaxIdleConns
+	}
+	if other.ConnMaxLifetime != 0 {
+		merged.ConnMaxLifetime = other.ConnMaxLifetime
+	}
+	if other.MaxRetries != 0 {
+		merged.MaxRetries = other.MaxRetries
+	}
+	if other.RetryBackoff != 0 {
+		merged.RetryBackoff = other.RetryBackoff
+	}
+	if other.RetryMaxBackoff != 0 {
+		merged.RetryMaxBackoff = other.RetryMaxBackoff
+	}
+	if other.ReadOnly {
+		merged.ReadOnly = true
+	}
+	if other.Backup != BackupNone {
+		merged.Backup = other.Backup
+	}
+	if other.SchemaChange != SchemaChangeRecreate {
+		merged.SchemaChange = other.SchemaChange
+	}
+	if other.OnlineSchemaChange != nil {
+		merged.OnlineSchemaChange = other.OnlineSchemaChange
+	}
+	if other.Alter != nil {
+		merged.Alter = other.Alter
+	}
+	if other.WriteLockTimeout != 0 {
+		merged.WriteLockTimeout = other.WriteLockTimeout
+	}
+	if other.ConfirmDrop != nil {
+		merged.ConfirmDrop = other.ConfirmDrop
+	}
+	if len(other.ReplicaDSNs) > 0 {
+		merged.ReplicaDSNs = other.ReplicaDSNs
+	}
+	if other.MaxReplicationLag != 0 {
+		merged.MaxReplicationLag = other.MaxReplicationLag
+	}
+	if other.ReplicaCheckInterval != 0 {
+		merged.ReplicaCheckInterval = other.ReplicaCheckInterval
+	}
+	for _, s := range []struct{ merged, other *string }{
+		{&merged.TLSCAFile, &other.TLSCAFile},
+		{&merged.TLSCertFile, &other.TLSCertFile},
+		{&merged.TLSKeyFile, &other.TLSKeyFile},
+		{&merged.TLSServerName, &other.TLSServerName},
+		{&merged.ServerPubKeyFile, &other.ServerPubKeyFile},
+	} {
+		if *s.other != "" {
+			*s.merged = *s.other
+		}
+	}
+	if other.PasswordProvider != nil {
+		merged.PasswordProvider = other.PasswordProvider
+	}
+	if len(other.SessionVariables) > 0 {
+		merged.SessionVariables = make(map[string]string, len(opts.SessionVariables)+len(other.SessionVariables))
+		for k, v := range opts.SessionVariables {
+			merged.SessionVariables[k] = v
+		}
+		for k, v := range other.SessionVariables {
+			merged.SessionVariables[k] = v
+		}
+	}
+	return &merged
+}
+
+// poolOption names the first option set which configures the pool, its sessions or its replicas,
+// and is empty when none is.
+func (opts *Options) poolOption() string {
+	switch {
+	case opts.MaxOpenConns != 0, opts.MaxIdleConns != 0, opts.ConnMaxLifetime != 0:
+		return "pool limits"
+	case len(opts.SessionVariables) > 0:
+		return "SessionVariables"
+	case opts.TLSCAFile != "", opts.TLSCertFile != "", opts.TLSKeyFile != "", opts.TLSServerName != "":
+		return "TLS"
+	case opts.ServerPubKeyFile != "":
+		return "ServerPubKeyFile"
+	case opts.PasswordProvider != nil:
+		return "PasswordProvider"
+	case len(opts.ReplicaDSNs) > 0, opts.MaxReplicationLag != 0, opts.ReplicaCheckInterval != 0:
+		return "replicas"
+	default:
+		return ""
+	}
+}
+
+// configureDSN adds the session variables of opts to dsn, where go-sql-driver sets them on every
+// new connection of the pool, and registers its TLS config and server public key.
+// It returns the registered TLS config, if any.
+func (opts *Options) configureDSN(dsn string) (string, *tls.Config, error) {
+	if len(opts.SessionVariables) == 0 && !opts.hasTLSFiles() && opts.ServerPubKeyFile == "" {
+		return dsn, nil, nil
+	}
+	cfg, err := gomysql.ParseDSN(dsn)
+	if err != nil {
+		return "", nil, err
+	}
+	if len(opts.SessionVariables) > 0 && cfg.Params == nil {
+		cfg.Params = make(map[string]string, len(opts.SessionVariables))
+	}
+	for name, value := range opts.SessionVariables {
+		cfg.Params[name] = quoteSessionValue(value)
+	}
+	var tlsCfg *tls.Config
+	if opts.hasTLSFiles() {
+		if tlsCfg, err = opts.registerTLSConfig(cfg); err != nil {
+			return "", nil, err
+		}
+	}
+	if opts.ServerPubKeyFile != "" {
+		if err := opts.registerServerPubKey(cfg); err != nil {
+			return "", nil, err
+		}
+	}
+	return cfg.FormatDSN(), tlsCfg, nil
+}
+
+func quoteSessionValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + strings.Replace(value, "'", "''", -1) + "'"
+}
@@ -0,0 +1,60 @@
+package mysql
+
+import (
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ParseDSNOptions(t *testing.T) {
+	dsn, opts, err := parseDSNOptions("root:pass@tcp(localhost:3306)/tamate?maxOpenConns=10&maxIdleConns=5&connMaxLifetime=1m&sql_mode=%27TRADITIONAL%27")
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, 10, opts.MaxOpenConns)
+	assert.Equal(t, 5, opts.MaxIdleConns)
+	assert.Equal(t, time.Minute, opts.ConnMaxLifetime)
+
+	cfg, err := gomysql.ParseDSN(dsn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{"sql_mode": "'TRADITIONAL'"}, cfg.Params)
+	}
+
+	_, _, err = parseDSNOptions("root:pass@tcp(localhost:3306)/tamate?connMaxLifetime=forever")
+	assert.Error(t, err)
+}
+
+func Test_ConfigureDSN(t *testing.T) {
+	opts := (&Options{MaxOpenConns: 10}).merge(&Options{
+		MaxIdleConns: 2,
+		SessionVariables: map[string]string{
+			"time_zone":                "+00:00",
+			"innodb_lock_wait_timeout": "5",
+			"sql_mode":                 "it's",
+		},
+	})
+	assert.Equal(t, 10, opts.MaxOpenConns)
+	assert.Equal(t, 2, opts.MaxIdleConns)
+
+	dsn, _, err := opts.configureDSN("root:pass@tcp(localhost:3306)/tamate")
+	if !assert.NoError(t, err) {
+		return
+	}
+	cfg, err := gomysql.ParseDSN(dsn)
+	if assert.NoError(t, err) {
+		assert.Equal(t, map[string]string{
+			"time_zone":                "'+00:00'",
+			"innodb_lock_wait_timeout": "5",
+			"sql_mode":                 "'it''s'",
+		}, cfg.Params)
+	}
+}
+
+func Test_PoolOption(t *testing.T) {
+	assert.Empty(t, (&Options{ReadOnly: true, MaxRetries: 3, WriteLockTimeout: time.Second}).poolOption())
+	assert.Equal(t, "pool limits", (&Options{MaxIdleConns: 2}).poolOption())
+	assert.Equal(t, "SessionVariables", (&Options{SessionVariables: map[string]string{"time_zone": "+00:00"}}).poolOption())
+	assert.Equal(t, "replicas", (&Options{ReplicaDSNs: []string{"root@tcp(replica)/tamatest"}}).poolOption())
+}
@@ -0,0 +1,345 @@
+package mysql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/go-tamate/tamate/driver"
+)
+
+// SchemaChangeMode is how SetSchema changes an existing table.
+type SchemaChangeMode string
+
+const (
+	// SchemaChangeRecreate drops the table and creates it again, losing its rows.
+	SchemaChangeRecreate SchemaChangeMode = ""
+	// SchemaChangeOnline copies the table to a shadow table with the new schema while writes go on,
+	// and swaps the shadow table in. Rows of columns both schemas have are kept.
+	SchemaChangeOnline SchemaChangeMode = "online"
+	// SchemaChangeAlter alters existing tables with ALTER TABLE, keeping their rows. See AlterOptions.
+	SchemaChangeAlter SchemaChangeMode = "alter"
+)
+
+const (
+	defaultOnlineChunkSize      = 1000
+	defaultThrottleInterval     = time.Second
+	defaultCutOverLockTimeout   = 3 * time.Second
+	defaultCutOverRetries       = 5
+	onlineSchemaChangeShadowTag = "_new"
+	onlineSchemaChangeOldTag    = "_old"
+)
+
+// OnlineSchemaChangeOptions tune SchemaChangeOnline, much like pt-online-schema-change.
+// Triggers on the table keep the shadow table current, so the user needs the TRIGGER privilege,
+// and SUPER or log_bin_trust_function_creators when the binary log is written.
+type OnlineSchemaChangeOptions struct {
+	// ChunkSize is the number of rows copied at once. It defaults to 1000.
+	ChunkSize int
+	// MaxReplicationLag pauses the copy while a replica lags further behind.
+	// It defaults to Options.MaxReplicationLag, and replicas are those of Options.ReplicaDSNs.
+	MaxReplicationLag time.Duration
+	// ThrottleInterval is the wait before the lag is checked again. It defaults to 1s.
+	ThrottleInterval time.Duration
+	// CutOverLockTimeout limits how long the swap waits for the metadata lock,
+	// during which writes to the table queue behind it. It defaults to 3s.
+	CutOverLockTimeout time.Duration
+	// CutOverRetries is how often a swap timing out is tried again. It defaults to 5.
+	CutOverRetries int
+	// KeepOldTable keeps the table as it was before the change under the name _<table>_old.
+	// With Options.Backup it is kept as a backup instead.
+	KeepOldTable bool
+}
+
+func (opts *OnlineSchemaChangeOptions) withDefaults(connOpts *Options) *OnlineSchemaChangeOptions {
+	o := OnlineSchemaChangeOptions{}
+	if opts != nil {
+		o = *opts
+	}
+	if o.ChunkSize <= 0 {
+		o.ChunkSize = defaultOnlineChunkSize
+	}
+	if o.MaxReplicationLag <= 0 && connOpts != nil {
+		o.MaxReplicationLag = connOpts.MaxReplicationLag
+	}
+	if o.ThrottleInterval <= 0 {
+		o.ThrottleInterval = defaultThrottleInterval
+	}
+	if o.CutOverLockTimeout <= 0 {
+		o.CutOverLockTimeout = defaultCutOverLockTimeout
+	}
+	if o.CutOverRetries <= 0 {
+		o.CutOverRetries = defaultCutOverRetries
+	}
+	return &o
+}
+
+// onlineSchemaChange names the tables and triggers of a change of one table.
+type onlineSchemaChange struct {
+	tableName    string
+	shadowName   string
+	oldName      string
+	triggerNames [3]string
+	columnNames  []string
+	pk           []string
+	// alterations turn a copy of the table into the shadow table
+	alterations []*Alteration
+	// keepOld keeps the old table under oldName
+	keepOld bool
+}
+
+func newOnlineSchemaChange(tableName string, current, sc *driver.Schema) (*onlineSchemaChange, error) {
+	if current.PrimaryKey == nil || len(current.PrimaryKey.ColumnNames) == 0 {
+		return nil, errors.New("primary key not found: " + tableName)
+	}
+	if sc.PrimaryKey == nil || !sameNames(current.PrimaryKey.ColumnNames, sc.PrimaryKey.ColumnNames) {
+		return nil, fmt.Errorf("primary key of %s must stay the same in an online schema change", tableName)
+	}
+
+	osc := &onlineSchemaChange{
+		tableName:  tableName,
+		shadowName: "_" + tableName + onlineSchemaChangeShadowTag,
+		oldName:    "_" + tableName + onlineSchemaChangeOldTag,
+		triggerNames: [3]string{
+			"_" + tableName + "_ins",
+			"_" + tableName + "_upd",
+			"_" + tableName + "_del",
+		},
+		pk: current.PrimaryKey.ColumnNames,
+	}
+	for _, name := range []string{osc.shadowName, osc.oldName, osc.triggerNames[0]} {
+		if len(name) > maxTableNameLen {
+			return nil, fmt.Errorf("name %s is longer than %d characters", name, maxTableNameLen)
+		}
+	}
+	for _, col := range current.Columns {
+		if hasColumn(sc, col.Name) {
+			osc.columnNames = append(osc.columnNames, col.Name)
+		}
+	}
+
+	osc.alterations = diffSchema(current, sc, nil)
+	for _, a := range osc.alterations {
+		switch {
+		// the copy would convert values without a word, and the primary key of converted values may collide
+		case a.Kind == AlterModifyColumn && a.Column.Type != a.Previous.Type:
+			return nil, fmt.Errorf("type of column %s of %s cannot change in an online schema change", a.Column.Name, tableName)
+		// the triggers write no value to added columns, which a NOT NULL column without a default refuses
+		case a.Kind == AlterAddColumn && a.Column.NotNull && !a.Column.AutoIncrement:
+			return nil, fmt.Errorf("column %s added to %s in an online schema change must be nullable", a.Column.Name, tableName)
+		}
+	}
+	return osc, nil
+}
+
+func sameNames(names, other []string) bool {
+	if len(names) != len(other) {
+		return false
+	}
+	for i := range names {
+		if names[i] != other[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// ChangeSchemaOnline changes a table to sc without blocking writes for long: a shadow table with the new schema
+// is filled in chunks by primary key, kept current by triggers, and swapped in with RENAME TABLE.
+// The primary key has to stay the same.
+func (c *Conn) ChangeSchemaOnline(ctx context.Context, tableName string, sc *driver.Schema, opts *OnlineSchemaChangeOptions) error {
+	if err := c.checkDrop(ctx, "ChangeSchemaOnline", tableName); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		return c.changeSchemaOnline(ctx, tableName, sc, opts)
+	})
+}
+
+func (c *Conn) changeSchemaOnline(ctx context.Context, tableName string, sc *driver.Schema, opts *OnlineSchemaChangeOptions) error {
+	opts = opts.withDefaults(c.opts)
+
+	current, err := getSchemaDB(ctx, c.db, tableName)
+	if err != nil {
+		return err
+	}
+	osc, err := newOnlineSchemaChange(tableName, current, sc)
+	if err != nil {
+		return err
+	}
+	if c.opts != nil && c.opts.Backup != BackupNone {
+		// the old table is the backup, so it is neither copied nor renamed once more
+		if osc.oldName, err = c.newBackupName(ctx, tableName); err != nil {
+			return err
+		}
+		osc.keepOld = true
+	} else {
+		osc.keepOld = opts.KeepOldTable
+	}
+
+	for _, name := range []string{osc.shadowName, osc.oldName} {
+		exists, err := tableExistsDB(ctx, c.db, name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			return fmt.Errorf("table %s exists, so another schema change of %s may be running or may have failed", name, tableName)
+		}
+	}
+
+	// the shadow table starts as a copy of the table, so that its indexes, partitions and the live
+	// definitions of its columns are kept, and only the columns which change are altered
+	v, err := c.serverVersion(ctx)
+	if err != nil {
+		return err
+	}
+	defs, err := getColumnDefinitions(ctx, c.db, v, tableName)
+	if err != nil {
+		return err
+	}
+	setColumnDefinitions(osc.alterations, defs)
+	if err := createTableLikeDB(ctx, c.db, tableName, osc.shadowName); err != nil {
+		return err
+	}
+	if len(osc.alterations) > 0 {
+		if err := alterTableDB(ctx, c.db, osc.shadowName, osc.alterations, AlterAlgorithmDefault, AlterLockDefault); err != nil {
+			if derr := dropTableDB(ctx, c.db, osc.shadowName); derr != nil {
+				return fmt.Errorf("%v (drop of shadow table: %v)", err, derr)
+			}
+			return err
+		}
+	}
+	if err := c.copyOnline(ctx, osc, current, opts); err != nil {
+		if cerr := c.cleanUpOnline(ctx, osc, osc.shadowName); cerr != nil {
+			return fmt.Errorf("%v (clean up: %v)", err, cerr)
+		}
+		return err
+	}
+	if osc.keepOld {
+		return c.dropTriggers(ctx, osc)
+	}
+	return c.cleanUpOnline(ctx, osc, osc.oldName)
+}
+
+// copyOnline copies the rows and swaps the shadow table in.
+func (c *Conn) copyOnline(ctx context.Context, osc *onlineSchemaChange, current *driver.Schema, opts *OnlineSchemaChangeOptions) error {
+	// triggers come first, so that no write during the copy is missed
+	if err := createCopyTriggersDB(ctx, c.db, osc.tableName, osc.shadowName, osc.columnNames, osc.pk, osc.triggerNames); err != nil {
+		return err
+	}
+
+	pkSchema, err := primaryKeySchema(current)
+	if err != nil {
+		return err
+	}
+	var lower []interface{}
+	for {
+		if err := c.throttle(ctx, opts); err != nil {
+			return err
+		}
+		// boundaries are read from the primary, as replicas may lag behind the triggers
+		upper, err := chunkBoundary(ctx, c.db, pkSchema, lower, opts.ChunkSize)
+		if err != nil {
+			return err
+		}
+		chunk := &Chunk{Lower: lower, Upper: upper}
+		err = c.retry(ctx, func(ctx context.Context) error {
+			return copyChunkDB(ctx, c.db, osc.tableName, osc.shadowName, osc.columnNames, osc.pk, chunk)
+		})
+		if err != nil {
+			return err
+		}
+		if upper == nil {
+			break
+		}
+		lower = upper
+	}
+	return c.cutOver(ctx, osc, opts)
+}
+
+// throttle waits while a replica lags further behind than allowed.
+func (c *Conn) throttle(ctx context.Context, opts *OnlineSchemaChangeOptions) error {
+	if opts.MaxReplicationLag <= 0 || c.replicas == nil {
+		return nil
+	}
+	for {
+		lagging := false
+		for _, r := range c.replicas.replicas {
+			// a replica which cannot tell its lag may be far behind as well
+			if lag, err := getReplicationLag(ctx, r.db); err != nil || lag > opts.MaxReplicationLag {
+				lagging = true
+				break
+			}
+		}
+		if !lagging {
+			return nil
+		}
+		select {
+		case <-time.After(opts.ThrottleInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// cutOver swaps the shadow table in with one RENAME TABLE. The rename waits for the metadata lock
+// no longer than the cut-over timeout, so that writes do not queue behind it for long, and is tried again.
+func (c *Conn) cutOver(ctx context.Context, osc *onlineSchemaChange, opts *OnlineSchemaChangeOptions) (err error) {
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	seconds := int(opts.CutOverLockTimeout / time.Second)
+	if seconds < 1 {
+		seconds = 1
+	}
+	if err := setLockWaitTimeoutDB(ctx, conn, seconds); err != nil {
+		return err
+	}
+	// the connection goes back to the pool, so its lock wait timeout is reset afterwards,
+	// even when ctx is cancelled
+	defer func() {
+		if rerr := setLockWaitTimeoutDB(context.Background(), conn, 0); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	renames := [][2]string{{osc.tableName, osc.oldName}, {osc.shadowName, osc.tableName}}
+	for attempt := 0; ; attempt++ {
+		err = renameTablesDB(ctx, conn, renames)
+		if err == nil || attempt >= opts.CutOverRetries || !isLockWaitTimeout(err) {
+			return err
+		}
+		select {
+		case <-time.After(opts.ThrottleInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+func isLockWaitTimeout(err error) bool {
+	me, ok := err.(*gomysql.MySQLError)
+	return ok && me.Number == errNoLockWaitTimeout
+}
+
+func (c *Conn) dropTriggers(ctx context.Context, osc *onlineSchemaChange) error {
+	for _, name := range osc.triggerNames {
+		if err := dropTriggerDB(ctx, c.db, name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cleanUpOnline drops the triggers and a table left over from the change.
+func (c *Conn) cleanUpOnline(ctx context.Context, osc *onlineSchemaChange, tableName string) error {
+	if err := c.dropTriggers(ctx, osc); err != nil {
+		return err
+	}
+	return dropTableDB(ctx, c.db, tableName)
+}
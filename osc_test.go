@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_OnlineSchemaChangeQueries(t *testing.T) {
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	current := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+			driver.NewColumn("old", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+	sc := &driver.Schema{
+		Name:       "user",
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+			driver.NewColumn("new", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+
+	osc, err := newOnlineSchemaChange("user", current, sc)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, "_user_new", osc.shadowName)
+	assert.Equal(t, []string{"id", "name"}, osc.columnNames)
+
+	qs, err := generateCreateCopyTriggerQueries("user", osc.shadowName, osc.columnNames, osc.pk, osc.triggerNames)
+	if assert.NoError(t, err) {
+		assert.Equal(t, []string{
+			"CREATE TRIGGER `_user_ins` AFTER INSERT ON `user` FOR EACH ROW REPLACE INTO `_user_new` (`id`, `name`) VALUES (NEW.`id`, NEW.`name`)",
+			"CREATE TRIGGER `_user_upd` AFTER UPDATE ON `user` FOR EACH ROW BEGIN DELETE IGNORE FROM `_user_new` WHERE `_user_new`.`id` <=> OLD.`id`; REPLACE INTO `_user_new` (`id`, `name`) VALUES (NEW.`id`, NEW.`name`); END",
+			"CREATE TRIGGER `_user_del` AFTER DELETE ON `user` FOR EACH ROW DELETE IGNORE FROM `_user_new` WHERE `_user_new`.`id` <=> OLD.`id`",
+		}, qs)
+	}
+
+	q, err := generateCopyChunkQuery("user", osc.shadowName, osc.columnNames, osc.pk, true, true)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "INSERT IGNORE INTO `_user_new` (`id`, `name`) SELECT `id`, `name` FROM `user` WHERE `id` > ? AND `id` <= ? LOCK IN SHARE MODE", q)
+	}
+
+	if assert.Len(t, osc.alterations, 2) {
+		assert.Equal(t, AlterAddColumn, osc.alterations[0].Kind)
+		assert.Equal(t, AlterDropColumn, osc.alterations[1].Kind)
+	}
+
+	// the triggers could not write a row without a value for an added NOT NULL column
+	sc.Columns[2] = driver.NewColumn("new", 2, driver.ColumnTypeString, true, false)
+	_, err = newOnlineSchemaChange("user", current, sc)
+	assert.Error(t, err)
+
+	// values would be converted during the copy
+	sc.Columns[2] = driver.NewColumn("old", 2, driver.ColumnTypeInt, false, false)
+	_, err = newOnlineSchemaChange("user", current, sc)
+	assert.Error(t, err)
+
+	sc.PrimaryKey = &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"name"}}
+	_, err = newOnlineSchemaChange("user", current, sc)
+	assert.Error(t, err)
+}
+
+func Test_ChangeSchemaOnline(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s?schemaChange=online", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	pk := &driver.Key{KeyType: driver.KeyTypePrimary, ColumnNames: []string{"id"}}
+	fakeSchema := &driver.Schema{
+		Name:       tableName,
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+	for i := 1; i <= 3; i++ {
+		_, err := insertRow(ConnectionTestUser, ConnectionTestPassword, dbName, tableName, &driver.Row{
+			Values: map[string]*driver.GenericColumnValue{
+				"id":   driver.NewGenericColumnValue(fakeSchema.Columns[0], i),
+				"name": driver.NewGenericColumnValue(fakeSchema.Columns[1], fmt.Sprintf("name%d", i)),
+			},
+		})
+		assert.NoError(t, err)
+	}
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// Adding a column keeps the rows
+	newSchema := &driver.Schema{
+		Name:       tableName,
+		PrimaryKey: pk,
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+			driver.NewColumn("note", 2, driver.ColumnTypeString, false, false),
+		},
+	}
+	assert.NoError(t, conn.SetSchema(ctx, tableName, newSchema))
+	sc, err := conn.GetSchema(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, sc.Columns, 3)
+	}
+	rows, err := conn.GetRows(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 3)
+	}
+}
@@ -0,0 +1,147 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// Partitioning is how a table is partitioned. Tables recreated by this driver keep it.
+type Partitioning struct {
+	TableName string
+	// Method is RANGE, LIST, HASH or KEY, with COLUMNS or LINEAR as the server names it, like RANGE COLUMNS.
+	Method string
+	// Expression is the expression or the columns partitioned by.
+	Expression string
+	// SubpartitionMethod and SubpartitionExpression are empty without subpartitions.
+	SubpartitionMethod     string
+	SubpartitionExpression string
+	// Subpartitions is the number of subpartitions of every partition.
+	Subpartitions int
+	Partitions    []*Partition
+}
+
+// Partition is a partition of a table.
+type Partition struct {
+	Name string
+	// Values are the bound of VALUES LESS THAN of a range partition, like 2020 or MAXVALUE,
+	// or the list of VALUES IN of a list partition, like 1,2,3.
+	Values  string
+	Comment string
+}
+
+// GetPartitioning reads the partitioning of a table, or returns nil when the table is not partitioned.
+func (c *Conn) GetPartitioning(ctx context.Context, tableName string) (*Partitioning, error) {
+	var result *Partitioning
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		partitionings, err := getPartitionings(ctx, c.reader(ctx), tableName)
+		if err != nil {
+			return err
+		}
+		if len(partitionings) > 0 {
+			result = partitionings[0]
+		}
+		return nil
+	})
+	return result, err
+}
+
+// getPartitionings reads the partitioned tables, or the one of tableName unless it is empty.
+func getPartitionings(ctx context.Context, db queryer, tableName string) ([]*Partitioning, error) {
+	rows, err := getPartitionsDB(ctx, db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partitionings []*Partitioning
+	var p *Partitioning
+	var last *Partition
+	for rows.Next() {
+		var name string
+		var partitionName string
+		var method string
+		var expression sql.NullString
+		var values sql.NullString
+		var comment string
+		var subpartitionName sql.NullString
+		var subpartitionMethod sql.NullString
+		var subpartitionExpression sql.NullString
+		if err := rows.Scan(&name, &partitionName, &method, &expression, &values, &comment, &subpartitionName, &subpartitionMethod, &subpartitionExpression); err != nil {
+			return nil, err
+		}
+
+		// rows are ordered by table, partition and subpartition
+		if p == nil || p.TableName != name {
+			p = &Partitioning{
+				TableName:              name,
+				Method:                 method,
+				Expression:             expression.String,
+				SubpartitionMethod:     subpartitionMethod.String,
+				SubpartitionExpression: subpartitionExpression.String,
+			}
+			partitionings = append(partitionings, p)
+			last = nil
+		}
+		if last == nil || last.Name != partitionName {
+			last = &Partition{Name: partitionName, Values: values.String, Comment: comment}
+			p.Partitions = append(p.Partitions, last)
+		}
+		if subpartitionName.Valid && len(p.Partitions) == 1 {
+			p.Subpartitions++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return partitionings, nil
+}
+
+// tablePartitioning reads the partitioning of a table to recreate, which may not exist.
+func (c *Conn) tablePartitioning(ctx context.Context, tableName string) (*Partitioning, error) {
+	partitionings, err := getPartitionings(ctx, c.db, tableName)
+	if err != nil || len(partitionings) == 0 {
+		return nil, err
+	}
+	return partitionings[0], nil
+}
+
+// AddPartitions adds partitions after the existing ones of a range or list partitioned table.
+// A range partition bounded by MAXVALUE has to be dropped or reorganized beforehand.
+func (c *Conn) AddPartitions(ctx context.Context, tableName string, partitions ...*Partition) error {
+	if err := c.checkWrite("AddPartitions"); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		p, err := c.tablePartitioning(ctx, tableName)
+		if err != nil {
+			return err
+		}
+		if p == nil {
+			return errors.New("table is not partitioned: " + tableName)
+		}
+		return addPartitionsDB(ctx, c.db, p, partitions)
+	})
+}
+
+// DropPartitions drops partitions of a range or list partitioned table, with their rows.
+// It asks ConfirmDrop for the table, and backs it up by copy when backups are configured.
+func (c *Conn) DropPartitions(ctx context.Context, tableName string, partitionNames ...string) error {
+	if err := c.checkDrop(ctx, "DropPartitions", tableName); err != nil {
+		return err
+	}
+	return c.withWriteLock(ctx, []string{tableName}, func(ctx context.Context) error {
+		// the table stays in place, so it is copied rather than renamed
+		if _, err := c.backupTable(ctx, tableName, copyBackupMode(c.backupMode())); err != nil {
+			return err
+		}
+		return dropPartitionsDB(ctx, c.db, tableName, partitionNames)
+	})
+}
+
+// GetPartitionRows reads the rows of one partition of a table.
+func (c *Conn) GetPartitionRows(ctx context.Context, tableName, partitionName string) ([]*driver.Row, error) {
+	return c.GetRowsWithQuery(ctx, tableName, &RowsQuery{Partitions: []string{partitionName}})
+}
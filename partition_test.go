@@ -0,0 +1,143 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_PartitionQueries(t *testing.T) {
+	sc := &driver.Schema{
+		Name: "log",
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id", "year"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("year", 1, driver.ColumnTypeInt, true, false),
+		},
+	}
+	p := &Partitioning{
+		TableName:  "log",
+		Method:     "RANGE",
+		Expression: "`year`",
+		Partitions: []*Partition{
+			{Name: "p2019", Values: "2020", Comment: "it's 2019"},
+			{Name: "pmax", Values: "MAXVALUE"},
+		},
+	}
+	q, err := generateCreatePartitionedTableQuery(sc, p)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE TABLE `log` (`id` INT NOT NULL, `year` INT NOT NULL, PRIMARY KEY (`id`, `year`)) PARTITION BY RANGE(`year`) (PARTITION `p2019` VALUES LESS THAN (2020) COMMENT = 'it''s 2019', PARTITION `pmax` VALUES LESS THAN MAXVALUE)", q)
+	}
+	q, err = generateCreatePartitionedTableQuery(sc, nil)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE TABLE `log` (`id` INT NOT NULL, `year` INT NOT NULL, PRIMARY KEY (`id`, `year`))", q)
+	}
+
+	hash := &Partitioning{
+		TableName:              "log",
+		Method:                 "LIST",
+		Expression:             "`year`",
+		SubpartitionMethod:     "HASH",
+		SubpartitionExpression: "`id`",
+		Subpartitions:          2,
+		Partitions:             []*Partition{{Name: "old", Values: "2018,2019"}},
+	}
+	clause, err := generatePartitionClause(hash)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "PARTITION BY LIST(`year`) SUBPARTITION BY HASH(`id`) SUBPARTITIONS 2 (PARTITION `old` VALUES IN (2018,2019))", clause)
+	}
+
+	q, err = generateAddPartitionsQuery(p, []*Partition{{Name: "p2020", Values: "2021"}})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ALTER TABLE `log` ADD PARTITION (PARTITION `p2020` VALUES LESS THAN (2021))", q)
+	}
+	_, err = generateAddPartitionsQuery(p, []*Partition{{Name: "p2020"}})
+	assert.Error(t, err)
+	q, err = generateDropPartitionsQuery("log", []string{"p2019", "p2020"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "ALTER TABLE `log` DROP PARTITION `p2019`, `p2020`", q)
+	}
+
+	q, _, err = generateSelectRowsByQuery(sc, &RowsQuery{Partitions: []string{"p2019"}, Limit: 10})
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SELECT * FROM `log` PARTITION (`p2019`) LIMIT 10", q)
+	}
+	_, _, err = generateSelectRowsByQuery(sc, &RowsQuery{Partitions: []string{"p`"}})
+	assert.Error(t, err)
+}
+
+func Test_Partitions(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+		},
+	}
+	fakeRow := &driver.Row{
+		Values: map[string]*driver.GenericColumnValue{
+			"id": driver.NewGenericColumnValue(fakeSchema.Columns[0], 150),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := conn.(*Conn)
+
+	p := &Partitioning{
+		TableName:  tableName,
+		Method:     "RANGE",
+		Expression: "`id`",
+		Partitions: []*Partition{{Name: "p100", Values: "100"}, {Name: "p200", Values: "200"}},
+	}
+	assert.NoError(t, createPartitionedTableDB(ctx, c.db, fakeSchema, p))
+
+	// Replacing rows keeps the partitioning
+	assert.NoError(t, c.SetRows(ctx, tableName, []*driver.Row{fakeRow}))
+	read, err := c.GetPartitioning(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Equal(t, p, read)
+	}
+
+	// Reading a single partition
+	rows, err := c.GetPartitionRows(ctx, tableName, "p100")
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 0)
+	}
+	rows, err = c.GetPartitionRows(ctx, tableName, "p200")
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 1)
+	}
+
+	// Adding and dropping partitions
+	assert.NoError(t, c.AddPartitions(ctx, tableName, &Partition{Name: "p300", Values: "300"}))
+	assert.NoError(t, c.DropPartitions(ctx, tableName, "p100"))
+	read, err = c.GetPartitioning(ctx, tableName)
+	if assert.NoError(t, err) && assert.Len(t, read.Partitions, 2) {
+		assert.Equal(t, "p200", read.Partitions[0].Name)
+		assert.Equal(t, "p300", read.Partitions[1].Name)
+	}
+}
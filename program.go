@@ -0,0 +1,292 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+const (
+	errNoRoutineNotFound = 1305
+	errNoTriggerNotFound = 1360
+	errNoEventNotFound   = 1539
+)
+
+// ProgramType is a type of stored program.
+type ProgramType string
+
+const (
+	ProgramFunction  ProgramType = "FUNCTION"
+	ProgramProcedure ProgramType = "PROCEDURE"
+	ProgramTrigger   ProgramType = "TRIGGER"
+	ProgramEvent     ProgramType = "EVENT"
+)
+
+// programTypes are in the order programs are created, as triggers and events may call routines.
+var programTypes = []ProgramType{ProgramFunction, ProgramProcedure, ProgramTrigger, ProgramEvent}
+
+func (t ProgramType) rank() int {
+	for i, pt := range programTypes {
+		if pt == t {
+			return i
+		}
+	}
+	return -1
+}
+
+// createColumn is the column of SHOW CREATE with the statement creating the program.
+func (t ProgramType) createColumn() string {
+	switch t {
+	case ProgramFunction:
+		return "Create Function"
+	case ProgramProcedure:
+		return "Create Procedure"
+	case ProgramTrigger:
+		return "SQL Original Statement"
+	case ProgramEvent:
+		return "Create Event"
+	default:
+		return ""
+	}
+}
+
+// StoredProgram is a trigger, routine or event of the connected database.
+type StoredProgram struct {
+	Type ProgramType
+	Name string
+	// TableName is the table of a trigger.
+	TableName string
+	// Definition is the CREATE statement of the program without its definer,
+	// so that it is created by the current user.
+	Definition string
+	// SQLMode is the sql_mode the program was created with, and runs with.
+	SQLMode string
+}
+
+// ProgramNotFoundError is returned for a stored program which does not exist.
+type ProgramNotFoundError struct {
+	Type ProgramType
+	Name string
+}
+
+func (e *ProgramNotFoundError) Error() string {
+	return fmt.Sprintf("%s %s not found", e.Type, e.Name)
+}
+
+var definerPattern = regexp.MustCompile("^(?i)(CREATE)\\s+DEFINER\\s*=\\s*(?:`[^`]*`|'[^']*'|[^\\s@]+)(?:@(?:`[^`]*`|'[^']*'|[^\\s]+))?\\s+")
+
+// stripDefiner removes the definer of a CREATE statement, which may not exist on another server.
+func stripDefiner(definition string) string {
+	return definerPattern.ReplaceAllString(definition, "$1 ")
+}
+
+// GetStoredPrograms reads the functions, procedures, triggers and events of the connected database
+// in the order they can be created. Definitions of routines need the privileges of SHOW CREATE.
+func (c *Conn) GetStoredPrograms(ctx context.Context) ([]*StoredProgram, error) {
+	var result []*StoredProgram
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getStoredPrograms(ctx)
+		return err
+	})
+	return result, err
+}
+
+func (c *Conn) getStoredPrograms(ctx context.Context) ([]*StoredProgram, error) {
+	// programs are read from the primary, as a replica tells its events disabled on the replica
+	db := c.db
+	rows, err := getStoredProgramsDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var programs []*StoredProgram
+	for rows.Next() {
+		p := &StoredProgram{}
+		var programType string
+		var actionOrder int
+		if err := rows.Scan(&programType, &p.Name, &p.TableName, &actionOrder); err != nil {
+			return nil, err
+		}
+		p.Type = ProgramType(programType)
+		programs = append(programs, p)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	for _, p := range programs {
+		if err := readStoredProgram(ctx, db, p); err != nil {
+			return nil, err
+		}
+	}
+	return programs, nil
+}
+
+// GetStoredProgram reads a program by type and name.
+func (c *Conn) GetStoredProgram(ctx context.Context, programType ProgramType, name string) (*StoredProgram, error) {
+	p := &StoredProgram{Type: programType, Name: name}
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		return readStoredProgram(ctx, c.db, p)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// readStoredProgram reads the definition of p by SHOW CREATE, and the table of a trigger.
+func readStoredProgram(ctx context.Context, db queryer, p *StoredProgram) error {
+	notFound := &ProgramNotFoundError{Type: p.Type, Name: p.Name}
+	if p.Type == ProgramTrigger && p.TableName == "" {
+		tableName, err := getTriggerTableDB(ctx, db, p.Name)
+		if err == sql.ErrNoRows {
+			return notFound
+		}
+		if err != nil {
+			return err
+		}
+		p.TableName = tableName
+	}
+
+	rows, err := showCreateProgramDB(ctx, db, p.Type, p.Name)
+	if me, ok := err.(*gomysql.MySQLError); ok {
+		switch me.Number {
+		case errNoRoutineNotFound, errNoTriggerNotFound, errNoEventNotFound:
+			return notFound
+		}
+	}
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	status, err := scanStatusRow(rows)
+	if err != nil {
+		return err
+	}
+	if status == nil {
+		return notFound
+	}
+	definition := status[p.Type.createColumn()]
+	if !definition.Valid {
+		return fmt.Errorf("definition of %s %s is hidden from the current user", p.Type, p.Name)
+	}
+	p.Definition = stripDefiner(definition.String)
+	p.SQLMode = status["sql_mode"].String
+	return nil
+}
+
+// sortStoredPrograms sorts programs by the order of their types, keeping the order of triggers of a table.
+func sortStoredPrograms(programs []*StoredProgram) ([]*StoredProgram, error) {
+	sorted := make([]*StoredProgram, len(programs))
+	copy(sorted, programs)
+	for _, p := range sorted {
+		if p.Type.rank() < 0 {
+			return nil, fmt.Errorf("unknown type of program %s: %s", p.Name, p.Type)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Type.rank() < sorted[j].Type.rank()
+	})
+	return sorted, nil
+}
+
+// SetStoredPrograms creates programs, replacing existing ones of the same type and name,
+// each with its own sql_mode. The tables of triggers have to exist. It stops at the first program
+// failing to be created, which is left as it was.
+func (c *Conn) SetStoredPrograms(ctx context.Context, programs []*StoredProgram) error {
+	if err := c.checkWrite("SetStoredPrograms"); err != nil {
+		return err
+	}
+	sorted, err := sortStoredPrograms(programs)
+	if err != nil {
+		return err
+	}
+	return c.setStoredPrograms(ctx, sorted)
+}
+
+func (c *Conn) setStoredPrograms(ctx context.Context, programs []*StoredProgram) (err error) {
+	// the sql_mode is a session variable, so the programs share one connection
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	sqlMode, err := getSQLModeDB(ctx, conn)
+	if err != nil {
+		return err
+	}
+	// the connection goes back to the pool, so its sql_mode is restored even when ctx is cancelled
+	defer func() {
+		if rerr := setSQLModeDB(context.Background(), conn, sqlMode); rerr != nil && err == nil {
+			err = rerr
+		}
+	}()
+
+	for _, p := range programs {
+		if p.Definition == "" {
+			return errors.New("definition of program must be set: " + p.Name)
+		}
+		if err := replaceStoredProgram(ctx, conn, p); err != nil {
+			return fmt.Errorf("%s %s: %v", p.Type, p.Name, err)
+		}
+	}
+	return nil
+}
+
+// replaceStoredProgram drops the program p replaces and creates p. The tables of a replaced trigger are
+// locked meanwhile, so that no write goes without it, and the replaced program is created again when p fails.
+func replaceStoredProgram(ctx context.Context, conn *sql.Conn, p *StoredProgram) (err error) {
+	original := &StoredProgram{Type: p.Type, Name: p.Name}
+	if err := readStoredProgram(ctx, conn, original); err != nil {
+		if _, ok := err.(*ProgramNotFoundError); !ok {
+			return err
+		}
+		original = nil
+	}
+
+	if original != nil && p.Type == ProgramTrigger {
+		tableNames := []string{original.TableName}
+		if p.TableName != "" && p.TableName != original.TableName {
+			tableNames = append(tableNames, p.TableName)
+		}
+		if err := lockTablesForWriteDB(ctx, conn, tableNames); err != nil {
+			return err
+		}
+		defer func() {
+			if uerr := execDB(context.Background(), conn, generateUnlockTablesQuery); uerr != nil && err == nil {
+				err = uerr
+			}
+		}()
+	}
+
+	if original != nil {
+		if err := dropProgramDB(ctx, conn, p.Type, p.Name); err != nil {
+			return err
+		}
+	}
+	if err := createStoredProgram(ctx, conn, p); err != nil {
+		if original == nil {
+			return err
+		}
+		if rerr := createStoredProgram(context.Background(), conn, original); rerr != nil {
+			return fmt.Errorf("%v (restoring the replaced one: %v)", err, rerr)
+		}
+		return err
+	}
+	return nil
+}
+
+func createStoredProgram(ctx context.Context, conn *sql.Conn, p *StoredProgram) error {
+	if err := setSQLModeDB(ctx, conn, p.SQLMode); err != nil {
+		return err
+	}
+	_, err := conn.ExecContext(ctx, p.Definition)
+	return err
+}
@@ -0,0 +1,100 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_StoredProgramQueries(t *testing.T) {
+	assert.Equal(t, "CREATE FUNCTION `double`(x INT) RETURNS int RETURN x * 2",
+		stripDefiner("CREATE DEFINER=`root`@`localhost` FUNCTION `double`(x INT) RETURNS int RETURN x * 2"))
+	assert.Equal(t, "create TRIGGER `t` BEFORE INSERT ON `user` FOR EACH ROW SET NEW.age = 0",
+		stripDefiner("create definer = 'app'@'%' TRIGGER `t` BEFORE INSERT ON `user` FOR EACH ROW SET NEW.age = 0"))
+	assert.Equal(t, "CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY DO DELETE FROM log",
+		stripDefiner("CREATE EVENT `e` ON SCHEDULE EVERY 1 DAY DO DELETE FROM log"))
+
+	q, err := generateShowCreateProgramQuery(ProgramProcedure, "refresh")
+	if assert.NoError(t, err) {
+		assert.Equal(t, "SHOW CREATE PROCEDURE `refresh`", q)
+	}
+	_, err = generateDropProgramQuery(ProgramType("TABLE"), "user")
+	assert.Error(t, err)
+
+	event := &StoredProgram{Type: ProgramEvent, Name: "e"}
+	first := &StoredProgram{Type: ProgramTrigger, Name: "b", TableName: "user"}
+	second := &StoredProgram{Type: ProgramTrigger, Name: "a", TableName: "user"}
+	function := &StoredProgram{Type: ProgramFunction, Name: "f"}
+	sorted, err := sortStoredPrograms([]*StoredProgram{event, first, second, function})
+	if assert.NoError(t, err) {
+		assert.Equal(t, []*StoredProgram{function, first, second, event}, sorted)
+	}
+}
+
+func Test_StoredPrograms(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := conn.(*Conn)
+
+	// Creating programs and reading them back
+	programs := []*StoredProgram{
+		{Type: ProgramTrigger, Name: "example_ins", TableName: tableName, Definition: "CREATE TRIGGER `example_ins` BEFORE INSERT ON `example` FOR EACH ROW SET NEW.id = double_id(NEW.id)"},
+		{Type: ProgramFunction, Name: "double_id", Definition: "CREATE FUNCTION `double_id`(x INT) RETURNS INT DETERMINISTIC RETURN x * 2"},
+	}
+	assert.NoError(t, c.SetStoredPrograms(ctx, programs))
+	read, err := c.GetStoredPrograms(ctx)
+	if assert.NoError(t, err) && assert.Len(t, read, 2) {
+		assert.Equal(t, ProgramFunction, read[0].Type)
+		assert.Equal(t, "example_ins", read[1].Name)
+		assert.Equal(t, tableName, read[1].TableName)
+		assert.NotContains(t, read[1].Definition, "DEFINER")
+	}
+
+	// Replacing them
+	assert.NoError(t, c.SetStoredPrograms(ctx, read))
+
+	// Reading a trigger by name
+	trigger, err := c.GetStoredProgram(ctx, ProgramTrigger, "example_ins")
+	if assert.NoError(t, err) {
+		assert.Equal(t, tableName, trigger.TableName)
+	}
+	_, err = c.GetStoredProgram(ctx, ProgramTrigger, "missing")
+	assert.IsType(t, &ProgramNotFoundError{}, err)
+
+	// A replacement failing to be created leaves the original in place
+	broken := &StoredProgram{Type: ProgramTrigger, Name: "example_ins", TableName: tableName, Definition: "CREATE TRIGGER `example_ins` BEFORE INSERT ON `example` FOR EACH ROW SET NEW.missing = 1"}
+	assert.Error(t, c.SetStoredPrograms(ctx, []*StoredProgram{broken}))
+	kept, err := c.GetStoredProgram(ctx, ProgramTrigger, "example_ins")
+	if assert.NoError(t, err) {
+		assert.Equal(t, trigger.Definition, kept.Definition)
+	}
+}
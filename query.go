@@ -7,14 +7,24 @@
 package mysql
 
 import (
+	"errors"
 	"fmt"
+	"reflect"
 	"strings"
 
 	"github.com/go-tamate/tamate/driver"
 )
 
 func generateGetInformationSchemaQuery(tableName string) (string, error) {
-	return fmt.Sprintf("SELECT COLUMN_NAME, ORDINAL_POSITION, COLUMN_TYPE, COLUMN_KEY, IS_NULLABLE, EXTRA FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() and TABLE_NAME = '%s'", tableName), nil
+	return fmt.Sprintf("SELECT COLUMN_NAME, ORDINAL_POSITION, COLUMN_TYPE, COLUMN_KEY, IS_NULLABLE, EXTRA FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() and TABLE_NAME = '%s' ORDER BY ORDINAL_POSITION", tableName), nil
+}
+
+func generateGetColumnDefinitionsQuery() (string, error) {
+	return "SELECT COLUMN_NAME, COLUMN_TYPE, COLUMN_DEFAULT, CHARACTER_SET_NAME, COLLATION_NAME, COLUMN_COMMENT, EXTRA FROM INFORMATION_SCHEMA.COLUMNS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? ORDER BY ORDINAL_POSITION", nil
+}
+
+func generateGetIndexesQuery() (string, error) {
+	return "SELECT INDEX_NAME, NON_UNIQUE, INDEX_TYPE, COLUMN_NAME, SUB_PART FROM INFORMATION_SCHEMA.STATISTICS WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND INDEX_NAME <> 'PRIMARY' ORDER BY INDEX_NAME, SEQ_IN_INDEX", nil
 }
 
 func generateCreateDBQuery(dbName string) (string, error) {
@@ -29,31 +39,103 @@ func generateCreateTableQuery(sc *driver.Schema) (string, error) {
 	var defs []string
 
 	for _, col := range sc.Columns {
-		ct, err := columnTypeFromGenericToMySQL(col.Type)
+		def, err := generateColumnDefinition(col)
 		if err != nil {
 			return "", err
 		}
-		def := fmt.Sprintf("`%s` %s", col.Name, ct)
+		defs = append(defs, def)
+	}
 
-		for _, n := range sc.PrimaryKey.ColumnNames {
-			if n == col.Name {
-				def += " PRIMARY KEY"
-				break
-			}
-		}
+	if sc.PrimaryKey != nil && len(sc.PrimaryKey.ColumnNames) > 0 {
+		defs = append(defs, fmt.Sprintf("PRIMARY KEY (%s)", quoteColumnNames(sc.PrimaryKey.ColumnNames)))
+	}
 
-		if col.NotNull {
-			def += " NOT NULL"
-		}
+	return fmt.Sprintf("CREATE TABLE `%s` (%s)", sc.Name, strings.Join(defs, ", ")), nil
+}
 
-		if col.AutoIncrement {
-			def += " AUTO INCREMENT"
-		}
+func generateColumnDefinition(col *driver.Column) (string, error) {
+	ct, err := columnTypeFromGenericToMySQL(col.Type)
+	if err != nil {
+		return "", err
+	}
+	def := fmt.Sprintf("`%s` %s", col.Name, ct)
 
-		defs = append(defs, def)
+	if col.NotNull {
+		def += " NOT NULL"
 	}
 
-	return fmt.Sprintf("CREATE TABLE `%s` (%s)", sc.Name, strings.Join(defs, ", ")), nil
+	if col.AutoIncrement {
+		def += " AUTO_INCREMENT"
+	}
+
+	return def, nil
+}
+
+// generateLiveColumnDefinition defines col with the live type, character set, default and comment of def,
+// which the generic type of col cannot tell.
+func generateLiveColumnDefinition(col *driver.Column, def *ColumnDefinition) string {
+	q := fmt.Sprintf("`%s` %s", col.Name, def.ColumnType)
+	if def.CharacterSet != "" {
+		q += " CHARACTER SET " + def.CharacterSet
+	}
+	if def.Collation != "" {
+		q += " COLLATE " + def.Collation
+	}
+
+	if col.NotNull {
+		q += " NOT NULL"
+	} else {
+		q += " NULL"
+	}
+
+	if def.Default != "" {
+		q += " DEFAULT " + def.Default
+	}
+	if def.OnUpdate != "" {
+		q += " ON UPDATE " + def.OnUpdate
+	}
+
+	if col.AutoIncrement {
+		q += " AUTO_INCREMENT"
+	}
+
+	if def.Comment != "" {
+		q += " COMMENT " + quoteString(def.Comment)
+	}
+	return q
+}
+
+// generateRetypedColumnDefinition defines col with the MySQL type of its new generic type, keeping
+// the default, comment, UNSIGNED and ON UPDATE of def where they apply to that type.
+// A default the new type cannot take fails the statement rather than being lost.
+func generateRetypedColumnDefinition(col *driver.Column, def *ColumnDefinition) (string, error) {
+	ct, err := columnTypeFromGenericToMySQL(col.Type)
+	if err != nil {
+		return "", err
+	}
+	retyped := &ColumnDefinition{ColumnType: ct, Default: def.Default, Comment: def.Comment}
+	switch col.Type {
+	case driver.ColumnTypeInt, driver.ColumnTypeFloat:
+		if strings.Contains(strings.ToLower(def.ColumnType), "unsigned") {
+			retyped.ColumnType += " UNSIGNED"
+		}
+	case driver.ColumnTypeDatetime:
+		retyped.OnUpdate = def.OnUpdate
+	}
+	return generateLiveColumnDefinition(col, retyped), nil
+}
+
+// quoteString quotes s as a string literal.
+func quoteString(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, "\\", "\\\\", -1), "'", "''", -1) + "'"
+}
+
+func quoteColumnNames(columnNames []string) string {
+	quoted := make([]string, len(columnNames))
+	for i, n := range columnNames {
+		quoted[i] = fmt.Sprintf("`%s`", n)
+	}
+	return strings.Join(quoted, ", ")
 }
 
 func generateDropTableQuery(tableName string) (string, error) {
@@ -61,7 +143,38 @@ func generateDropTableQuery(tableName string) (string, error) {
 }
 
 func generateSelectRowsQuery(tableName string) (string, error) {
-	return fmt.Sprintf("SELECT id, name FROM %s", tableName), nil
+	return fmt.Sprintf("SELECT * FROM `%s`", tableName), nil
+}
+
+func generateSelectRowsWhereQuery(tableName, where string) (string, error) {
+	if where == "" {
+		return generateSelectRowsQuery(tableName)
+	}
+	return fmt.Sprintf("SELECT * FROM `%s` WHERE %s", tableName, where), nil
+}
+
+// generateInCondition returns a condition matching any of the given tuples of columnNames.
+func generateInCondition(columnNames []string, tupleCount int) (string, error) {
+	if len(columnNames) == 0 || tupleCount == 0 {
+		return "", errors.New("empty IN condition")
+	}
+	placeholders := make([]string, len(columnNames))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	tuple := strings.Join(placeholders, ", ")
+	if len(columnNames) > 1 {
+		tuple = "(" + tuple + ")"
+	}
+	tuples := make([]string, tupleCount)
+	for i := range tuples {
+		tuples[i] = tuple
+	}
+	lhs := quoteColumnNames(columnNames)
+	if len(columnNames) > 1 {
+		lhs = "(" + lhs + ")"
+	}
+	return fmt.Sprintf("%s IN (%s)", lhs, strings.Join(tuples, ", ")), nil
 }
 
 func generateInsertRowQuery(tableName string, row *driver.Row) (string, error) {
@@ -72,3 +185,554 @@ func generateInsertRowQuery(tableName string, row *driver.Row) (string, error) {
 	}
 	return fmt.Sprintf("INSERT INTO `%s` (%s) VALUES (%s)", tableName, strings.Join(columnNames, ", "), strings.Join(values, ", ")), nil
 }
+
+func generateGetDatabaseInformationSchemaQuery() (string, error) {
+	return "SELECT c.TABLE_NAME, c.COLUMN_NAME, c.ORDINAL_POSITION, c.COLUMN_TYPE, c.COLUMN_KEY, c.IS_NULLABLE, c.EXTRA FROM INFORMATION_SCHEMA.COLUMNS c JOIN INFORMATION_SCHEMA.TABLES t ON t.TABLE_SCHEMA = c.TABLE_SCHEMA AND t.TABLE_NAME = c.TABLE_NAME WHERE c.TABLE_SCHEMA = DATABASE() AND t.TABLE_TYPE = 'BASE TABLE' ORDER BY c.TABLE_NAME, c.ORDINAL_POSITION", nil
+}
+
+func generateGetForeignKeysQuery() (string, error) {
+	return "SELECT k.CONSTRAINT_NAME, k.TABLE_NAME, k.COLUMN_NAME, k.REFERENCED_TABLE_NAME, k.REFERENCED_COLUMN_NAME, r.UPDATE_RULE, r.DELETE_RULE FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE k JOIN INFORMATION_SCHEMA.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.TABLE_NAME = k.TABLE_NAME AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME WHERE k.TABLE_SCHEMA = DATABASE() AND k.REFERENCED_TABLE_SCHEMA = DATABASE() ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION", nil
+}
+
+func generateAddForeignKeyQuery(fk *ForeignKey) (string, error) {
+	if len(fk.ColumnNames) == 0 || len(fk.ColumnNames) != len(fk.ReferencedColumnNames) {
+		return "", fmt.Errorf("invalid foreign key columns: %s", fk.Name)
+	}
+	q := fmt.Sprintf("ALTER TABLE `%s` ADD CONSTRAINT `%s` FOREIGN KEY (%s) REFERENCES `%s` (%s)", fk.TableName, fk.Name, quoteColumnNames(fk.ColumnNames), fk.ReferencedTableName, quoteColumnNames(fk.ReferencedColumnNames))
+	if fk.OnUpdate != "" {
+		q += " ON UPDATE " + fk.OnUpdate
+	}
+	if fk.OnDelete != "" {
+		q += " ON DELETE " + fk.OnDelete
+	}
+	return q, nil
+}
+
+func generateDeleteRowsQuery(tableName string) (string, error) {
+	return fmt.Sprintf("DELETE FROM `%s`", tableName), nil
+}
+
+func generateSetForeignKeyChecksQuery(enabled bool) (string, error) {
+	if enabled {
+		return "SET FOREIGN_KEY_CHECKS = 1", nil
+	}
+	return "SET FOREIGN_KEY_CHECKS = 0", nil
+}
+
+func generateSelectRowsByQuery(sc *driver.Schema, rq *RowsQuery) (string, []interface{}, error) {
+	if err := validateRowsQuery(sc, rq); err != nil {
+		return "", nil, err
+	}
+
+	var conds []string
+	var args []interface{}
+	for _, f := range rq.Filters {
+		switch f.Operator {
+		case OperatorIsNull, OperatorIsNotNull:
+			conds = append(conds, fmt.Sprintf("`%s` %s", f.Column, f.Operator))
+		case OperatorIn:
+			v := reflect.ValueOf(f.Value)
+			if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+				return "", nil, fmt.Errorf("IN value of %s is not a slice", f.Column)
+			}
+			if v.Len() == 0 {
+				// nothing is in an empty set
+				conds = append(conds, "FALSE")
+				continue
+			}
+			cond, err := generateInCondition([]string{f.Column}, v.Len())
+			if err != nil {
+				return "", nil, err
+			}
+			conds = append(conds, cond)
+			for i := 0; i < v.Len(); i++ {
+				args = append(args, v.Index(i).Interface())
+			}
+		default:
+			conds = append(conds, fmt.Sprintf("`%s` %s ?", f.Column, f.Operator))
+			args = append(args, f.Value)
+		}
+	}
+
+	q := fmt.Sprintf("SELECT * FROM `%s`", sc.Name)
+	if len(rq.Partitions) > 0 {
+		q += fmt.Sprintf(" PARTITION (%s)", quoteColumnNames(rq.Partitions))
+	}
+	if len(conds) > 0 {
+		q += " WHERE " + strings.Join(conds, " AND ")
+	}
+	if len(rq.OrderBy) > 0 {
+		orders := make([]string, len(rq.OrderBy))
+		for i, o := range rq.OrderBy {
+			orders[i] = fmt.Sprintf("`%s`", o.Column)
+			if o.Descending {
+				orders[i] += " DESC"
+			}
+		}
+		q += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if rq.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", rq.Limit)
+	}
+	return q, args, nil
+}
+
+func generateStartSnapshotQueries() ([]string, error) {
+	return []string{
+		"SET TRANSACTION ISOLATION LEVEL REPEATABLE READ",
+		"START TRANSACTION WITH CONSISTENT SNAPSHOT, READ ONLY",
+	}, nil
+}
+
+func generateShowMasterStatusQuery() (string, error) {
+	return "SHOW MASTER STATUS", nil
+}
+
+func generateShowSlaveStatusQuery() (string, error) {
+	return "SHOW SLAVE STATUS", nil
+}
+
+func generateFlushTablesWithReadLockQuery() (string, error) {
+	return "FLUSH TABLES WITH READ LOCK", nil
+}
+
+func generateLockTablesForWriteQuery(tableNames []string) (string, error) {
+	if len(tableNames) == 0 {
+		return "", errors.New("no tables to lock")
+	}
+	locks := make([]string, len(tableNames))
+	for i, tableName := range tableNames {
+		locks[i] = fmt.Sprintf("`%s` WRITE", tableName)
+	}
+	return "LOCK TABLES " + strings.Join(locks, ", "), nil
+}
+
+func generateUnlockTablesQuery() (string, error) {
+	return "UNLOCK TABLES", nil
+}
+
+func generateCommitQuery() (string, error) {
+	return "COMMIT", nil
+}
+
+func generateRollbackQuery() (string, error) {
+	return "ROLLBACK", nil
+}
+
+// generateTupleComparison compares columnNames with as many placeholders as a row constructor.
+func generateTupleComparison(columnNames []string, operator string) string {
+	if len(columnNames) == 1 {
+		return fmt.Sprintf("`%s` %s ?", columnNames[0], operator)
+	}
+	placeholders := make([]string, len(columnNames))
+	for i := range placeholders {
+		placeholders[i] = "?"
+	}
+	return fmt.Sprintf("(%s) %s (%s)", quoteColumnNames(columnNames), operator, strings.Join(placeholders, ", "))
+}
+
+// generateSelectRowsSinceQuery selects rows after a watermark, ordered by the watermark column
+// and the primary key so that rows sharing a watermark value are split between batches consistently.
+func generateSelectRowsSinceQuery(sc *driver.Schema, column string, hasWatermark bool, limit int) (string, error) {
+	if sc.PrimaryKey == nil || len(sc.PrimaryKey.ColumnNames) == 0 {
+		return "", errors.New("primary key not found: " + sc.Name)
+	}
+	pk := sc.PrimaryKey.ColumnNames
+
+	where := fmt.Sprintf("`%s` IS NOT NULL", column)
+	if hasWatermark {
+		where = fmt.Sprintf("(`%s` > ? OR (`%s` = ? AND %s))", column, column, generateTupleComparison(pk, ">"))
+	}
+	q, err := generateSelectRowsWhereQuery(sc.Name, where)
+	if err != nil {
+		return "", err
+	}
+	q += fmt.Sprintf(" ORDER BY `%s`, %s", column, quoteColumnNames(pk))
+	if limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", limit)
+	}
+	return q, nil
+}
+
+// generateChunkCondition restricts rows to the primary key range of a chunk, lower exclusive and upper inclusive.
+func generateChunkCondition(pk []string, hasLower, hasUpper bool) string {
+	var conds []string
+	if hasLower {
+		conds = append(conds, generateTupleComparison(pk, ">"))
+	}
+	if hasUpper {
+		conds = append(conds, generateTupleComparison(pk, "<="))
+	}
+	return strings.Join(conds, " AND ")
+}
+
+func generateChunkBoundaryQuery(sc *driver.Schema, hasLower bool, chunkSize int) (string, error) {
+	if sc.PrimaryKey == nil || len(sc.PrimaryKey.ColumnNames) == 0 {
+		return "", errors.New("primary key not found: " + sc.Name)
+	}
+	pk := quoteColumnNames(sc.PrimaryKey.ColumnNames)
+	q := fmt.Sprintf("SELECT %s FROM `%s`", pk, sc.Name)
+	if hasLower {
+		q += " WHERE " + generateChunkCondition(sc.PrimaryKey.ColumnNames, true, false)
+	}
+	return q + fmt.Sprintf(" ORDER BY %s LIMIT 1 OFFSET %d", pk, chunkSize-1), nil
+}
+
+func generateChunkChecksumQuery(sc *driver.Schema, hasLower, hasUpper bool) (string, error) {
+	if sc.PrimaryKey == nil || len(sc.PrimaryKey.ColumnNames) == 0 {
+		return "", errors.New("primary key not found: " + sc.Name)
+	}
+	columns := make([]string, len(sc.Columns))
+	nulls := make([]string, len(sc.Columns))
+	for i, col := range sc.Columns {
+		columns[i] = fmt.Sprintf("`%s`", col.Name)
+		nulls[i] = fmt.Sprintf("ISNULL(`%s`)", col.Name)
+	}
+	// CONCAT_WS skips NULL, so the NULL flags tell NULL apart from empty values
+	row := fmt.Sprintf("CONCAT_WS('#', %s, CONCAT(%s))", strings.Join(columns, ", "), strings.Join(nulls, ", "))
+	q := fmt.Sprintf("SELECT COUNT(*), COALESCE(BIT_XOR(CRC32(%s)), 0) FROM `%s`", row, sc.Name)
+	if where := generateChunkCondition(sc.PrimaryKey.ColumnNames, hasLower, hasUpper); where != "" {
+		q += " WHERE " + where
+	}
+	return q, nil
+}
+
+func generateGetTableNamesQuery() (string, error) {
+	return "SELECT TABLE_NAME FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_TYPE = 'BASE TABLE' ORDER BY TABLE_NAME", nil
+}
+
+func generateTableExistsQuery() (string, error) {
+	return "SELECT COUNT(*) FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", nil
+}
+
+func generateCreateTableLikeQuery(tableName, copyName string) (string, error) {
+	return fmt.Sprintf("CREATE TABLE `%s` LIKE `%s`", copyName, tableName), nil
+}
+
+func generateCopyTableQueries(tableName, copyName string) ([]string, error) {
+	like, err := generateCreateTableLikeQuery(tableName, copyName)
+	if err != nil {
+		return nil, err
+	}
+	return []string{
+		like,
+		fmt.Sprintf("INSERT INTO `%s` SELECT * FROM `%s`", copyName, tableName),
+	}, nil
+}
+
+// generateRenameTablesQuery renames pairs of old and new names at once, so that tables can be swapped atomically.
+func generateRenameTablesQuery(renames [][2]string) (string, error) {
+	if len(renames) == 0 {
+		return "", errors.New("no table to rename")
+	}
+	parts := make([]string, len(renames))
+	for i, r := range renames {
+		parts[i] = fmt.Sprintf("`%s` TO `%s`", r[0], r[1])
+	}
+	return "RENAME TABLE " + strings.Join(parts, ", "), nil
+}
+
+// generatePrimaryKeyMatch matches the primary key of a row of tableName to the values of row, like OLD in a trigger.
+func generatePrimaryKeyMatch(tableName string, pk []string, row string) string {
+	conds := make([]string, len(pk))
+	for i, name := range pk {
+		conds[i] = fmt.Sprintf("`%s`.`%s` <=> %s.`%s`", tableName, name, row, name)
+	}
+	return strings.Join(conds, " AND ")
+}
+
+// generateCreateCopyTriggerQueries creates triggers on tableName which apply every write to copyName,
+// for the columns both tables have.
+func generateCreateCopyTriggerQueries(tableName, copyName string, columnNames, pk []string, triggerNames [3]string) ([]string, error) {
+	if len(columnNames) == 0 || len(pk) == 0 {
+		return nil, errors.New("columns and primary key of triggers must be set")
+	}
+	values := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		values[i] = fmt.Sprintf("NEW.`%s`", name)
+	}
+	replace := fmt.Sprintf("REPLACE INTO `%s` (%s) VALUES (%s)", copyName, quoteColumnNames(columnNames), strings.Join(values, ", "))
+	del := fmt.Sprintf("DELETE IGNORE FROM `%s` WHERE %s", copyName, generatePrimaryKeyMatch(copyName, pk, "OLD"))
+	return []string{
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER INSERT ON `%s` FOR EACH ROW %s", triggerNames[0], tableName, replace),
+		// the primary key may change, so the old row goes first
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER UPDATE ON `%s` FOR EACH ROW BEGIN %s; %s; END", triggerNames[1], tableName, del, replace),
+		fmt.Sprintf("CREATE TRIGGER `%s` AFTER DELETE ON `%s` FOR EACH ROW %s", triggerNames[2], tableName, del),
+	}, nil
+}
+
+func generateDropTriggerQuery(triggerName string) (string, error) {
+	return fmt.Sprintf("DROP TRIGGER IF EXISTS `%s`", triggerName), nil
+}
+
+// generateCopyChunkQuery copies a chunk of rows to copyName. Rows already copied by triggers are newer, so they are kept.
+func generateCopyChunkQuery(tableName, copyName string, columnNames, pk []string, hasLower, hasUpper bool) (string, error) {
+	if len(columnNames) == 0 || len(pk) == 0 {
+		return "", errors.New("columns and primary key of copy must be set")
+	}
+	q := fmt.Sprintf("INSERT IGNORE INTO `%s` (%s) SELECT %s FROM `%s`", copyName, quoteColumnNames(columnNames), quoteColumnNames(columnNames), tableName)
+	if where := generateChunkCondition(pk, hasLower, hasUpper); where != "" {
+		q += " WHERE " + where
+	}
+	// the shared lock keeps the chunk from changing between the read and the triggers
+	return q + " LOCK IN SHARE MODE", nil
+}
+
+// generateSetLockWaitTimeoutQuery sets the timeout of metadata locks, or resets it to the global one when seconds is 0.
+func generateSetLockWaitTimeoutQuery(seconds int) (string, error) {
+	if seconds < 0 {
+		return "", errors.New("lock wait timeout must not be negative")
+	}
+	if seconds == 0 {
+		return "SET SESSION lock_wait_timeout = DEFAULT", nil
+	}
+	return fmt.Sprintf("SET SESSION lock_wait_timeout = %d", seconds), nil
+}
+
+func generateGetServerVersionQuery() (string, error) {
+	return "SELECT VERSION()", nil
+}
+
+// generateAlterTableQuery alters a table at once. An empty algorithm or lock leaves the choice to the server.
+// generateIndexDefinition writes an index as it follows ADD in ALTER TABLE.
+func generateIndexDefinition(index *Index) string {
+	parts := make([]string, 0, len(index.Columns))
+	for _, col := range index.Columns {
+		if col.Length > 0 {
+			parts = append(parts, fmt.Sprintf("`%s`(%d)", col.Name, col.Length))
+		} else {
+			parts = append(parts, fmt.Sprintf("`%s`", col.Name))
+		}
+	}
+	kind := "INDEX"
+	switch {
+	case index.special():
+		kind = strings.ToUpper(index.Type) + " INDEX"
+	case index.Unique:
+		kind = "UNIQUE INDEX"
+	}
+	return fmt.Sprintf("%s `%s` (%s)", kind, index.Name, strings.Join(parts, ", "))
+}
+
+func generateAlterTableQuery(tableName string, alterations []*Alteration, algorithm AlterAlgorithm, lock AlterLock) (string, error) {
+	if len(alterations) == 0 {
+		return "", errors.New("no alteration of table: " + tableName)
+	}
+	clauses := make([]string, 0, len(alterations)+2)
+	for _, a := range alterations {
+		clause, err := a.clause()
+		if err != nil {
+			return "", err
+		}
+		clauses = append(clauses, clause)
+	}
+	if algorithm != "" {
+		clauses = append(clauses, "ALGORITHM="+string(algorithm))
+	}
+	if lock != "" {
+		clauses = append(clauses, "LOCK="+string(lock))
+	}
+	return fmt.Sprintf("ALTER TABLE `%s` %s", tableName, strings.Join(clauses, ", ")), nil
+}
+
+// generateGetLockQuery waits for an advisory lock for a number of seconds, and returns 1 when it is acquired.
+func generateGetLockQuery() (string, error) {
+	return "SELECT GET_LOCK(?, ?)", nil
+}
+
+func generateReleaseLockQuery() (string, error) {
+	return "SELECT RELEASE_LOCK(?)", nil
+}
+
+func generateGetConnectionIDQuery() (string, error) {
+	return "SELECT CONNECTION_ID()", nil
+}
+
+func generateKillConnectionQuery(id int64) (string, error) {
+	return fmt.Sprintf("KILL CONNECTION %d", id), nil
+}
+
+func generateGetDatabaseNameQuery() (string, error) {
+	return "SELECT DATABASE()", nil
+}
+
+func generateCreateMigrationsTableQuery(tableName string) (string, error) {
+	return fmt.Sprintf("CREATE TABLE IF NOT EXISTS `%s` (`version` BIGINT NOT NULL, `name` VARCHAR(255) NOT NULL, `checksum` CHAR(64) NOT NULL, `applied_at` TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (`version`))", tableName), nil
+}
+
+func generateSelectMigrationsQuery(tableName string) (string, error) {
+	return fmt.Sprintf("SELECT `version`, `name`, `checksum`, UNIX_TIMESTAMP(`applied_at`) FROM `%s` ORDER BY `version`", tableName), nil
+}
+
+func generateInsertMigrationQuery(tableName string) (string, error) {
+	return fmt.Sprintf("INSERT INTO `%s` (`version`, `name`, `checksum`) VALUES (?, ?, ?)", tableName), nil
+}
+
+func generateDeleteMigrationQuery(tableName string) (string, error) {
+	return fmt.Sprintf("DELETE FROM `%s` WHERE `version` = ?", tableName), nil
+}
+
+// generateGetViewsQuery reads the views, or the one named by an argument.
+func generateGetViewsQuery(byName bool) (string, error) {
+	q := "SELECT TABLE_NAME, VIEW_DEFINITION, CHECK_OPTION, SECURITY_TYPE, IS_UPDATABLE FROM INFORMATION_SCHEMA.VIEWS WHERE TABLE_SCHEMA = DATABASE()"
+	if byName {
+		q += " AND TABLE_NAME = ?"
+	}
+	return q + " ORDER BY TABLE_NAME", nil
+}
+
+// generateCreateViewQuery replaces a view. The definer is the current user.
+func generateCreateViewQuery(v *View) (string, error) {
+	if v.Definition == "" {
+		return "", errors.New("definition of view must be set: " + v.Name)
+	}
+	q := "CREATE OR REPLACE"
+	if v.SecurityType != "" {
+		q += " SQL SECURITY " + v.SecurityType
+	}
+	q += fmt.Sprintf(" VIEW `%s` AS %s", v.Name, v.Definition)
+	if v.CheckOption != "" && v.CheckOption != "NONE" {
+		q += fmt.Sprintf(" WITH %s CHECK OPTION", v.CheckOption)
+	}
+	return q, nil
+}
+
+func generateGetTableTypeQuery() (string, error) {
+	return "SELECT TABLE_TYPE FROM INFORMATION_SCHEMA.TABLES WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ?", nil
+}
+
+// generateGetStoredProgramsQuery lists the stored programs in the order they can be created,
+// with triggers of a table in the order they run.
+func generateGetStoredProgramsQuery() (string, error) {
+	return "SELECT p.program_type, p.name, p.table_name, p.action_order FROM (" +
+		"SELECT ROUTINE_TYPE AS program_type, ROUTINE_NAME AS name, '' AS table_name, 0 AS action_order FROM INFORMATION_SCHEMA.ROUTINES WHERE ROUTINE_SCHEMA = DATABASE() " +
+		"UNION ALL SELECT 'TRIGGER', TRIGGER_NAME, EVENT_OBJECT_TABLE, ACTION_ORDER FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE() " +
+		"UNION ALL SELECT 'EVENT', EVENT_NAME, '', 0 FROM INFORMATION_SCHEMA.EVENTS WHERE EVENT_SCHEMA = DATABASE()" +
+		") p ORDER BY FIELD(p.program_type, 'FUNCTION', 'PROCEDURE', 'TRIGGER', 'EVENT'), p.table_name, p.action_order, p.name", nil
+}
+
+func generateShowCreateProgramQuery(programType ProgramType, name string) (string, error) {
+	if programType.rank() < 0 {
+		return "", fmt.Errorf("unknown program type: %s", programType)
+	}
+	return fmt.Sprintf("SHOW CREATE %s `%s`", programType, name), nil
+}
+
+func generateGetTriggerTableQuery() (string, error) {
+	return "SELECT EVENT_OBJECT_TABLE FROM INFORMATION_SCHEMA.TRIGGERS WHERE TRIGGER_SCHEMA = DATABASE() AND TRIGGER_NAME = ?", nil
+}
+
+func generateDropProgramQuery(programType ProgramType, name string) (string, error) {
+	if programType.rank() < 0 {
+		return "", fmt.Errorf("unknown program type: %s", programType)
+	}
+	return fmt.Sprintf("DROP %s IF EXISTS `%s`", programType, name), nil
+}
+
+func generateGetSQLModeQuery() (string, error) {
+	return "SELECT @@SESSION.sql_mode", nil
+}
+
+func generateSetSQLModeQuery() (string, error) {
+	return "SET SESSION sql_mode = ?", nil
+}
+
+// generateGetPartitionsQuery reads the partitions of every partitioned table, or of the one named by an argument.
+func generateGetPartitionsQuery(byName bool) (string, error) {
+	q := "SELECT TABLE_NAME, PARTITION_NAME, PARTITION_METHOD, PARTITION_EXPRESSION, PARTITION_DESCRIPTION, PARTITION_COMMENT, SUBPARTITION_NAME, SUBPARTITION_METHOD, SUBPARTITION_EXPRESSION FROM INFORMATION_SCHEMA.PARTITIONS WHERE TABLE_SCHEMA = DATABASE() AND PARTITION_NAME IS NOT NULL"
+	if byName {
+		q += " AND TABLE_NAME = ?"
+	}
+	return q + " ORDER BY TABLE_NAME, PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION", nil
+}
+
+// generatePartitionDefinition defines a partition of p, with the values its method needs.
+func generatePartitionDefinition(p *Partitioning, partition *Partition) (string, error) {
+	if partition.Name == "" || strings.Contains(partition.Name, "`") {
+		return "", fmt.Errorf("invalid partition name: %s", partition.Name)
+	}
+	def := fmt.Sprintf("PARTITION `%s`", partition.Name)
+	switch {
+	case strings.HasPrefix(p.Method, "RANGE"):
+		if partition.Values == "" {
+			return "", errors.New("values of range partition must be set: " + partition.Name)
+		}
+		// only the bound of a single expression stands without parentheses
+		if p.Method == "RANGE" && partition.Values == "MAXVALUE" {
+			def += " VALUES LESS THAN MAXVALUE"
+		} else {
+			def += fmt.Sprintf(" VALUES LESS THAN (%s)", partition.Values)
+		}
+	case strings.HasPrefix(p.Method, "LIST"):
+		if partition.Values == "" {
+			return "", errors.New("values of list partition must be set: " + partition.Name)
+		}
+		def += fmt.Sprintf(" VALUES IN (%s)", partition.Values)
+	}
+	if partition.Comment != "" {
+		def += fmt.Sprintf(" COMMENT = '%s'", strings.Replace(partition.Comment, "'", "''", -1))
+	}
+	return def, nil
+}
+
+func generatePartitionDefinitions(p *Partitioning, partitions []*Partition) (string, error) {
+	if len(partitions) == 0 {
+		return "", errors.New("no partition of table: " + p.TableName)
+	}
+	defs := make([]string, len(partitions))
+	for i, partition := range partitions {
+		def, err := generatePartitionDefinition(p, partition)
+		if err != nil {
+			return "", err
+		}
+		defs[i] = def
+	}
+	return "(" + strings.Join(defs, ", ") + ")", nil
+}
+
+// generatePartitionClause is the PARTITION BY clause of CREATE TABLE.
+func generatePartitionClause(p *Partitioning) (string, error) {
+	if p.Method == "" {
+		return "", errors.New("partition method must be set: " + p.TableName)
+	}
+	clause := fmt.Sprintf("PARTITION BY %s(%s)", p.Method, p.Expression)
+	if p.SubpartitionMethod != "" {
+		clause += fmt.Sprintf(" SUBPARTITION BY %s(%s)", p.SubpartitionMethod, p.SubpartitionExpression)
+		if p.Subpartitions > 0 {
+			clause += fmt.Sprintf(" SUBPARTITIONS %d", p.Subpartitions)
+		}
+	}
+	defs, err := generatePartitionDefinitions(p, p.Partitions)
+	if err != nil {
+		return "", err
+	}
+	return clause + " " + defs, nil
+}
+
+// generateCreatePartitionedTableQuery creates a table partitioned by p, or not partitioned when p is nil.
+func generateCreatePartitionedTableQuery(sc *driver.Schema, p *Partitioning) (string, error) {
+	q, err := generateCreateTableQuery(sc)
+	if err != nil || p == nil {
+		return q, err
+	}
+	clause, err := generatePartitionClause(p)
+	if err != nil {
+		return "", err
+	}
+	return q + " " + clause, nil
+}
+
+func generateAddPartitionsQuery(p *Partitioning, partitions []*Partition) (string, error) {
+	defs, err := generatePartitionDefinitions(p, partitions)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("ALTER TABLE `%s` ADD PARTITION %s", p.TableName, defs), nil
+}
+
+func generateDropPartitionsQuery(tableName string, partitionNames []string) (string, error) {
+	if len(partitionNames) == 0 {
+		return "", errors.New("no partition to drop of table: " + tableName)
+	}
+	return fmt.Sprintf("ALTER TABLE `%s` DROP PARTITION %s", tableName, quoteColumnNames(partitionNames)), nil
+}
@@ -0,0 +1,140 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const defaultReplicaCheckInterval = time.Second
+
+// replicaSet balances reads over replicas, skipping the ones which are down or lag too far behind.
+type replicaSet struct {
+	replicas      []*replica
+	maxLag        time.Duration
+	checkInterval time.Duration
+	next          uint32
+}
+
+type replica struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	healthy   bool
+}
+
+// openReplicas opens the replica DSNs of opts, or returns nil when there are none.
+func (opts *Options) openReplicas() (*replicaSet, error) {
+	if len(opts.ReplicaDSNs) == 0 {
+		return nil, nil
+	}
+	rs := &replicaSet{
+		maxLag:        opts.MaxReplicationLag,
+		checkInterval: opts.ReplicaCheckInterval,
+	}
+	if rs.checkInterval <= 0 {
+		rs.checkInterval = defaultReplicaCheckInterval
+	}
+	for _, dsn := range opts.ReplicaDSNs {
+		db, _, err := opts.openDB(dsn)
+		if err != nil {
+			if cerr := rs.close(); cerr != nil {
+				return nil, cerr
+			}
+			return nil, err
+		}
+		rs.replicas = append(rs.replicas, &replica{db: db})
+	}
+	return rs, nil
+}
+
+// pick returns the next healthy replica in turn, or nil when none is healthy.
+func (rs *replicaSet) pick(ctx context.Context) *sql.DB {
+	start := atomic.AddUint32(&rs.next, 1)
+	for i := range rs.replicas {
+		r := rs.replicas[(int(start)+i)%len(rs.replicas)]
+		if r.isHealthy(ctx, rs.maxLag, rs.checkInterval) {
+			return r.db
+		}
+	}
+	return nil
+}
+
+func (rs *replicaSet) close() error {
+	var firstErr error
+	for _, r := range rs.replicas {
+		if err := r.db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// isHealthy checks the replica at most once per interval, so that reads do not wait on checks.
+func (r *replica) isHealthy(ctx context.Context, maxLag, interval time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if time.Since(r.checkedAt) < interval {
+		return r.healthy
+	}
+	r.healthy = checkReplica(ctx, r.db, maxLag) == nil
+	r.checkedAt = time.Now()
+	return r.healthy
+}
+
+func checkReplica(ctx context.Context, db *sql.DB, maxLag time.Duration) error {
+	if maxLag <= 0 {
+		return db.PingContext(ctx)
+	}
+	lag, err := getReplicationLag(ctx, db)
+	if err != nil {
+		return err
+	}
+	if lag > maxLag {
+		return &ReplicationLagError{Lag: lag, MaxLag: maxLag}
+	}
+	return nil
+}
+
+// getReplicationLag reads how far the replica is behind its source.
+// A server which is not a replica has no lag.
+func getReplicationLag(ctx context.Context, db queryer) (time.Duration, error) {
+	rows, err := showSlaveStatusDB(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+
+	status, err := scanStatusRow(rows)
+	if err != nil || status == nil {
+		return 0, err
+	}
+	seconds, ok := status["Seconds_Behind_Master"]
+	if !ok {
+		seconds = status["Seconds_Behind_Source"]
+	}
+	// NULL while replication is stopped
+	if !seconds.Valid {
+		return 0, errors.New("replication is not running")
+	}
+	n, err := strconv.ParseInt(seconds.String, 10, 64)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// ReplicationLagError is the reason a replica lagging too far behind is skipped.
+type ReplicationLagError struct {
+	Lag    time.Duration
+	MaxLag time.Duration
+}
+
+func (e *ReplicationLagError) Error() string {
+	return "replication lag " + e.Lag.String() + " exceeds " + e.MaxLag.String()
+}
@@ -0,0 +1,51 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReplicaSetPick(t *testing.T) {
+	var replicas []*replica
+	for i := 0; i < 3; i++ {
+		// sql.Open does not connect, and the health below is fresh, so nothing is checked
+		db, err := sql.Open(driverName, "root:pass@tcp(localhost:3306)/tamate")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		replicas = append(replicas, &replica{db: db, checkedAt: time.Now(), healthy: i != 1})
+	}
+	rs := &replicaSet{replicas: replicas, checkInterval: time.Hour}
+
+	picked := make(map[int]int)
+	for i := 0; i < 6; i++ {
+		db := rs.pick(context.Background())
+		for j, r := range replicas {
+			if r.db == db {
+				picked[j]++
+			}
+		}
+	}
+	// the turn of the unhealthy replica passes to the next one
+	assert.Equal(t, map[int]int{0: 2, 2: 4}, picked)
+
+	replicas[0].healthy = false
+	replicas[2].healthy = false
+	assert.Nil(t, rs.pick(context.Background()))
+}
+
+func Test_ParseReplicaOptions(t *testing.T) {
+	_, opts, err := parseDSNOptions("root:pass@tcp(localhost:3306)/tamate?maxReplicationLag=10s")
+	if assert.NoError(t, err) {
+		assert.Equal(t, 10*time.Second, opts.MaxReplicationLag)
+	}
+
+	merged := opts.merge(&Options{ReplicaDSNs: []string{"root:pass@tcp(replica:3306)/tamate"}})
+	assert.Equal(t, 10*time.Second, merged.MaxReplicationLag)
+	assert.Len(t, merged.ReplicaDSNs, 1)
+}
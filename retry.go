@@ -0,0 +1,91 @@
+package mysql
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"math/rand"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+const (
+	defaultRetryBackoff    = 100 * time.Millisecond
+	defaultRetryMaxBackoff = 5 * time.Second
+)
+
+// MySQL error numbers worth another attempt.
+const (
+	errNoTooManyConnections = 1040
+	errNoServerShutdown     = 1053
+	errNoLockWaitTimeout    = 1205
+	errNoDeadlock           = 1213
+	errNoServerGone         = 2006
+	errNoServerLost         = 2013
+)
+
+// IsRetryable reports whether err is transient, like a deadlock, a lock wait timeout or a lost connection.
+// Other errors, like syntax errors or duplicate keys, fail the same way on every attempt.
+func IsRetryable(err error) bool {
+	switch err {
+	case sqldriver.ErrBadConn, gomysql.ErrInvalidConn:
+		return true
+	}
+	if me, ok := err.(*gomysql.MySQLError); ok {
+		switch me.Number {
+		case errNoTooManyConnections, errNoServerShutdown, errNoLockWaitTimeout, errNoDeadlock, errNoServerGone, errNoServerLost:
+			return true
+		}
+	}
+	return false
+}
+
+type retryContextKey struct{}
+
+// retry runs fn until it succeeds, fails with an error that is not retryable or runs out of retries.
+// fn has to be safe to repeat as a whole, like a read or a transaction.
+// Operations nested in a retried one run once, so that retries do not multiply.
+func (c *Conn) retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.opts == nil || c.opts.MaxRetries <= 0 || ctx.Value(retryContextKey{}) != nil {
+		return fn(ctx)
+	}
+	ctx = context.WithValue(ctx, retryContextKey{}, true)
+
+	backoff := c.opts.RetryBackoff
+	if backoff <= 0 {
+		backoff = defaultRetryBackoff
+	}
+	maxBackoff := c.opts.RetryMaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = defaultRetryMaxBackoff
+	}
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= c.opts.MaxRetries || !IsRetryable(err) {
+			return err
+		}
+		select {
+		case <-time.After(jitter(backoff)):
+		case <-ctx.Done():
+			return err
+		}
+		if backoff *= 2; backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// retryRead is retry for reads, which cannot be repeated on a snapshot connection.
+func (c *Conn) retryRead(ctx context.Context, fn func(ctx context.Context) error) error {
+	if c.snapshot != nil {
+		return fn(ctx)
+	}
+	return c.retry(ctx, fn)
+}
+
+// jitter spreads retries of concurrent clients over the upper half of d.
+func jitter(d time.Duration) time.Duration {
+	half := d / 2
+	return half + time.Duration(rand.Int63n(int64(half)+1))
+}
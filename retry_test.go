@@ -0,0 +1,64 @@
+package mysql
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsRetryable(t *testing.T) {
+	assert.True(t, IsRetryable(&gomysql.MySQLError{Number: 1213}))
+	assert.True(t, IsRetryable(&gomysql.MySQLError{Number: 1205}))
+	assert.True(t, IsRetryable(sqldriver.ErrBadConn))
+	assert.True(t, IsRetryable(gomysql.ErrInvalidConn))
+	assert.False(t, IsRetryable(&gomysql.MySQLError{Number: 1062}))
+	assert.False(t, IsRetryable(errors.New("schema not found: user")))
+}
+
+func Test_Retry(t *testing.T) {
+	c := &Conn{opts: &Options{MaxRetries: 2, RetryBackoff: time.Millisecond}}
+	deadlock := &gomysql.MySQLError{Number: 1213}
+
+	attempts := 0
+	err := c.retry(context.Background(), func(ctx context.Context) error {
+		attempts++
+		if attempts < 3 {
+			return deadlock
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = c.retry(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return deadlock
+	})
+	assert.Equal(t, deadlock, err)
+	assert.Equal(t, 3, attempts)
+
+	attempts = 0
+	err = c.retry(context.Background(), func(ctx context.Context) error {
+		attempts++
+		return &gomysql.MySQLError{Number: 1062}
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, attempts)
+
+	// nested operations run once within the retried one
+	attempts = 0
+	err = c.retry(context.Background(), func(ctx context.Context) error {
+		return c.retry(ctx, func(ctx context.Context) error {
+			attempts++
+			return deadlock
+		})
+	})
+	assert.Equal(t, deadlock, err)
+	assert.Equal(t, 3, attempts)
+}
@@ -0,0 +1,61 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReadOnlyError is returned by write operations of a read only connection before any SQL runs.
+type ReadOnlyError struct {
+	Operation string
+}
+
+func (e *ReadOnlyError) Error() string {
+	return fmt.Sprintf("%s is not allowed on a read only connection", e.Operation)
+}
+
+// DropNotConfirmedError is returned when ConfirmDrop does not answer with the confirmation of a table.
+type DropNotConfirmedError struct {
+	TableName string
+}
+
+func (e *DropNotConfirmedError) Error() string {
+	return fmt.Sprintf("drop of table %s is not confirmed", e.TableName)
+}
+
+// ConfirmDropFunc asks for the confirmation of dropping a table, like a prompt of tooling.
+// The drop goes ahead only when it returns DropConfirmation(tableName).
+type ConfirmDropFunc func(ctx context.Context, tableName string) (string, error)
+
+// DropConfirmation is the answer confirming the drop of a table.
+func DropConfirmation(tableName string) string {
+	return "yes, drop table " + tableName
+}
+
+// checkWrite refuses operation on a read only connection.
+func (c *Conn) checkWrite(operation string) error {
+	if c.opts != nil && c.opts.ReadOnly {
+		return &ReadOnlyError{Operation: operation}
+	}
+	return nil
+}
+
+// checkDrop refuses operation on a read only connection, and asks ConfirmDrop for each table it drops.
+func (c *Conn) checkDrop(ctx context.Context, operation string, tableNames ...string) error {
+	if err := c.checkWrite(operation); err != nil {
+		return err
+	}
+	if c.opts == nil || c.opts.ConfirmDrop == nil {
+		return nil
+	}
+	for _, tableName := range tableNames {
+		answer, err := c.opts.ConfirmDrop(ctx, tableName)
+		if err != nil {
+			return err
+		}
+		if answer != DropConfirmation(tableName) {
+			return &DropNotConfirmedError{TableName: tableName}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package mysql
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ReadOnly(t *testing.T) {
+	_, opts, err := parseDSNOptions("root:pass@tcp(localhost:3306)/tamate?readOnly=true")
+	if !assert.NoError(t, err) {
+		return
+	}
+	// no pool is opened, so any SQL would fail differently
+	c := &Conn{opts: opts}
+	ctx := context.Background()
+
+	err = c.SetSchema(ctx, "user", &driver.Schema{Name: "user"})
+	assert.Equal(t, &ReadOnlyError{Operation: "SetSchema"}, err)
+	err = c.SetRows(ctx, "user", nil)
+	assert.Equal(t, &ReadOnlyError{Operation: "SetRows"}, err)
+	err = c.SetDatabaseSchema(ctx, &DatabaseSchema{})
+	assert.Equal(t, &ReadOnlyError{Operation: "SetDatabaseSchema"}, err)
+	err = c.LoadRows(ctx, nil, nil)
+	assert.Equal(t, &ReadOnlyError{Operation: "LoadRows"}, err)
+}
+
+func Test_ConfirmDrop(t *testing.T) {
+	var asked []string
+	c := &Conn{opts: &Options{
+		ConfirmDrop: func(ctx context.Context, tableName string) (string, error) {
+			asked = append(asked, tableName)
+			if tableName == "user" {
+				return DropConfirmation(tableName), nil
+			}
+			return "yes", nil
+		},
+	}}
+
+	err := c.checkDrop(context.Background(), "SetDatabaseSchema", "user", "item")
+	assert.Equal(t, &DropNotConfirmedError{TableName: "item"}, err)
+	assert.Equal(t, []string{"user", "item"}, asked)
+	assert.Equal(t, "yes, drop table user", DropConfirmation("user"))
+	assert.NoError(t, c.checkDrop(context.Background(), "SetSchema", "user"))
+}
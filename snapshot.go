@@ -0,0 +1,150 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// BinlogPosition is a position in the binary log, by file and offset and by executed GTID set.
+// It is empty when the server does not write a binary log.
+type BinlogPosition struct {
+	File     string
+	Position uint32
+	GTIDSet  string
+}
+
+type SnapshotOptions struct {
+	// LockTables holds FLUSH TABLES WITH READ LOCK while the snapshot starts, so that the position
+	// matches the snapshot exactly even under concurrent writes. It requires the RELOAD privilege.
+	LockTables bool
+}
+
+type snapshot struct {
+	conn     *sql.Conn
+	position *BinlogPosition
+}
+
+// StartSnapshot opens a consistent snapshot transaction. Until EndSnapshot every read of c,
+// like GetSchema and GetRows, sees the database as of the start of the snapshot.
+func (c *Conn) StartSnapshot(ctx context.Context, opts *SnapshotOptions) (*BinlogPosition, error) {
+	if c.snapshot != nil {
+		return nil, errors.New("snapshot is already started")
+	}
+	if opts == nil {
+		opts = &SnapshotOptions{}
+	}
+
+	conn, err := c.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := startSnapshot(ctx, conn, opts)
+	if err != nil {
+		if ferr := finishSnapshot(conn, generateRollbackQuery); ferr != nil {
+			return nil, fmt.Errorf("%v (rollback: %v)", err, ferr)
+		}
+		return nil, err
+	}
+
+	c.snapshot = &snapshot{conn: conn, position: pos}
+	return pos, nil
+}
+
+func startSnapshot(ctx context.Context, conn *sql.Conn, opts *SnapshotOptions) (pos *BinlogPosition, err error) {
+	if opts.LockTables {
+		if err := execDB(ctx, conn, generateFlushTablesWithReadLockQuery); err != nil {
+			return nil, err
+		}
+		// the connection goes back to the pool, so the global lock must not outlive this call
+		defer func() {
+			if uerr := execDB(context.Background(), conn, generateUnlockTablesQuery); uerr != nil && err == nil {
+				pos, err = nil, uerr
+			}
+		}()
+	}
+	if err := startSnapshotDB(ctx, conn); err != nil {
+		return nil, err
+	}
+	return getBinlogPosition(ctx, conn)
+}
+
+func getBinlogPosition(ctx context.Context, db queryer) (*BinlogPosition, error) {
+	rows, err := showMasterStatusDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	status, err := scanStatusRow(rows)
+	if err != nil {
+		return nil, err
+	}
+	pos := &BinlogPosition{}
+	if status == nil {
+		return pos, nil
+	}
+	pos.File = status["File"].String
+	if p := status["Position"]; p.Valid {
+		n, err := strconv.ParseUint(p.String, 10, 32)
+		if err != nil {
+			return nil, err
+		}
+		pos.Position = uint32(n)
+	}
+	pos.GTIDSet = status["Executed_Gtid_Set"].String
+	return pos, nil
+}
+
+// scanStatusRow reads the first row of a SHOW ... STATUS statement by column name,
+// as the columns differ between server versions. It returns nil when there is no row.
+func scanStatusRow(rows *sql.Rows) (map[string]sql.NullString, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	if !rows.Next() {
+		return nil, rows.Err()
+	}
+	values := make([]sql.NullString, len(columns))
+	ptrs := make([]interface{}, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := rows.Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	status := make(map[string]sql.NullString, len(columns))
+	for i, column := range columns {
+		status[column] = values[i]
+	}
+	return status, nil
+}
+
+// SnapshotPosition returns the position of the running snapshot, or nil without one.
+func (c *Conn) SnapshotPosition() *BinlogPosition {
+	if c.snapshot == nil {
+		return nil
+	}
+	return c.snapshot.position
+}
+
+// EndSnapshot finishes the snapshot started by StartSnapshot.
+func (c *Conn) EndSnapshot() error {
+	if c.snapshot == nil {
+		return errors.New("snapshot is not started")
+	}
+	conn := c.snapshot.conn
+	c.snapshot = nil
+	return finishSnapshot(conn, generateCommitQuery)
+}
+
+func finishSnapshot(conn *sql.Conn, finish func() (string, error)) error {
+	err := execDB(context.Background(), conn, finish)
+	if cerr := conn.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
@@ -0,0 +1,72 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Snapshot(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	newFakeRow := func(id int, name string) *driver.Row {
+		return &driver.Row{
+			Values: map[string]*driver.GenericColumnValue{
+				"id":   driver.NewGenericColumnValue(fakeSchema.Columns[0], id),
+				"name": driver.NewGenericColumnValue(fakeSchema.Columns[1], name),
+			},
+		}
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+	_, err := insertRow(ConnectionTestUser, ConnectionTestPassword, dbName, tableName, newFakeRow(1, "before"))
+	assert.NoError(t, err)
+
+	// Open connection
+	conn, err := newMySQLConn(dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+
+	// Reading in snapshot
+	pos, err := conn.StartSnapshot(ctx, nil)
+	if assert.NoError(t, err) {
+		assert.NotNil(t, pos)
+	}
+	_, err = insertRow(ConnectionTestUser, ConnectionTestPassword, dbName, tableName, newFakeRow(2, "after"))
+	assert.NoError(t, err)
+
+	rows, err := conn.GetRows(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 1)
+	}
+
+	// Reading after snapshot
+	assert.NoError(t, conn.EndSnapshot())
+	rows, err = conn.GetRows(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 2)
+	}
+}
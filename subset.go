@@ -0,0 +1,247 @@
+package mysql
+
+import (
+	"context"
+	sqldriver "database/sql/driver"
+	"fmt"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+const subsetBatchSize = 100
+
+// SubsetSeed selects the rows of a table a subset starts from.
+// Where is a SQL condition whose placeholders are bound to Args.
+type SubsetSeed struct {
+	TableName string
+	Where     string
+	Args      []interface{}
+}
+
+// GetSubset extracts the rows matched by seeds and closes them over foreign keys.
+// Every row referenced by an extracted row is extracted as well, and so is every row referencing
+// a seed row or one of its referencing rows. Rows only pulled in as references do not pull in
+// their other referencing rows, which would otherwise spread the subset over the whole database.
+// Masking rules apply to the result, which can be written with LoadRows.
+func (c *Conn) GetSubset(ctx context.Context, seeds []*SubsetSeed) (map[string][]*driver.Row, error) {
+	var result map[string][]*driver.Row
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getSubset(ctx, seeds)
+		return err
+	})
+	return result, err
+}
+
+func (c *Conn) getSubset(ctx context.Context, seeds []*SubsetSeed) (map[string][]*driver.Row, error) {
+	// every read of the subset goes to one connection, so that the rows are consistent
+	db := c.reader(ctx)
+	fks, err := c.getForeignKeys(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	s := &subset{
+		db:      db,
+		fks:     fks,
+		schemas: make(map[string]*driver.Schema),
+		rows:    make(map[string][]*driver.Row),
+		seen:    make(map[string]map[string]bool),
+	}
+
+	for _, seed := range seeds {
+		sc, err := s.schema(ctx, seed.TableName)
+		if err != nil {
+			return nil, err
+		}
+		rows, err := selectSchemaRowsDB(ctx, db, sc, nil, seed.Where, seed.Args...)
+		if err != nil {
+			return nil, err
+		}
+		s.add(seed.TableName, sc, rows, true)
+	}
+
+	for len(s.queue) > 0 {
+		item := s.queue[0]
+		s.queue = s.queue[1:]
+		if err := s.expand(ctx, item); err != nil {
+			return nil, err
+		}
+	}
+
+	// foreign keys are followed with the original values, so masking comes last
+	for tableName, rows := range s.rows {
+		if err := maskRows(c.maskingRules[tableName], rows); err != nil {
+			return nil, err
+		}
+	}
+	return s.rows, nil
+}
+
+type subsetItem struct {
+	tableName         string
+	rows              []*driver.Row
+	followReferencing bool
+}
+
+type subset struct {
+	db      queryer
+	fks     []*ForeignKey
+	schemas map[string]*driver.Schema
+	rows    map[string][]*driver.Row
+	// seen records whether the referencing rows of an extracted row are followed
+	seen  map[string]map[string]bool
+	queue []*subsetItem
+}
+
+func (s *subset) schema(ctx context.Context, tableName string) (*driver.Schema, error) {
+	if sc, ok := s.schemas[tableName]; ok {
+		return sc, nil
+	}
+	sc, err := getSchemaDB(ctx, s.db, tableName)
+	if err != nil {
+		return nil, err
+	}
+	s.schemas[tableName] = sc
+	return sc, nil
+}
+
+func (s *subset) add(tableName string, sc *driver.Schema, rows []*driver.Row, followReferencing bool) {
+	seen, ok := s.seen[tableName]
+	if !ok {
+		seen = make(map[string]bool)
+		s.seen[tableName] = seen
+	}
+
+	var queued []*driver.Row
+	for _, row := range rows {
+		key := rowKey(sc, row)
+		followed, extracted := seen[key]
+		if !extracted {
+			s.rows[tableName] = append(s.rows[tableName], row)
+		}
+		if extracted && (followed || !followReferencing) {
+			continue
+		}
+		seen[key] = followReferencing
+		queued = append(queued, row)
+	}
+	if len(queued) > 0 {
+		s.queue = append(s.queue, &subsetItem{tableName: tableName, rows: queued, followReferencing: followReferencing})
+	}
+}
+
+func (s *subset) expand(ctx context.Context, item *subsetItem) error {
+	for _, fk := range s.fks {
+		if fk.TableName == item.tableName {
+			if err := s.follow(ctx, item.rows, fk.ColumnNames, fk.ReferencedTableName, fk.ReferencedColumnNames, false); err != nil {
+				return err
+			}
+		}
+		if fk.ReferencedTableName == item.tableName && item.followReferencing {
+			if err := s.follow(ctx, item.rows, fk.ReferencedColumnNames, fk.TableName, fk.ColumnNames, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// follow extracts the rows of tableName whose columnNames match the fromColumnNames of rows.
+func (s *subset) follow(ctx context.Context, rows []*driver.Row, fromColumnNames []string, tableName string, columnNames []string, followReferencing bool) error {
+	tuples, err := columnTuples(rows, fromColumnNames)
+	if err != nil {
+		return err
+	}
+	if len(tuples) == 0 {
+		return nil
+	}
+	sc, err := s.schema(ctx, tableName)
+	if err != nil {
+		return err
+	}
+
+	for start := 0; start < len(tuples); start += subsetBatchSize {
+		end := start + subsetBatchSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+		where, err := generateInCondition(columnNames, end-start)
+		if err != nil {
+			return err
+		}
+		var args []interface{}
+		for _, tuple := range tuples[start:end] {
+			args = append(args, tuple...)
+		}
+
+		matched, err := selectSchemaRowsDB(ctx, s.db, sc, nil, where, args...)
+		if err != nil {
+			return err
+		}
+		s.add(tableName, sc, matched, followReferencing)
+	}
+	return nil
+}
+
+// columnTuples returns the distinct values of columnNames in rows, skipping tuples containing NULL.
+func columnTuples(rows []*driver.Row, columnNames []string) ([][]interface{}, error) {
+	var tuples [][]interface{}
+	seen := make(map[string]bool)
+	for _, row := range rows {
+		tuple := make([]interface{}, len(columnNames))
+		keys := make([]string, len(columnNames))
+		hasNull := false
+		for i, name := range columnNames {
+			cv, ok := row.Values[name]
+			if !ok {
+				return nil, fmt.Errorf("column not found: %s", name)
+			}
+			v, err := sqlValue(cv.Value)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				hasNull = true
+				break
+			}
+			tuple[i] = v
+			keys[i] = fmt.Sprintf("%v", v)
+		}
+		key := strings.Join(keys, "\x00")
+		if hasNull || seen[key] {
+			continue
+		}
+		seen[key] = true
+		tuples = append(tuples, tuple)
+	}
+	return tuples, nil
+}
+
+// sqlValue unwraps the sql.Null* values scanned by GetRows.
+func sqlValue(v interface{}) (interface{}, error) {
+	if valuer, ok := v.(sqldriver.Valuer); ok {
+		return valuer.Value()
+	}
+	return v, nil
+}
+
+// rowKey identifies a row by its primary key, or by all of its values when the table has none.
+func rowKey(sc *driver.Schema, row *driver.Row) string {
+	var columnNames []string
+	if sc.PrimaryKey != nil && len(sc.PrimaryKey.ColumnNames) > 0 {
+		columnNames = sc.PrimaryKey.ColumnNames
+	} else {
+		for _, col := range sc.Columns {
+			columnNames = append(columnNames, col.Name)
+		}
+	}
+
+	keys := make([]string, len(columnNames))
+	for i, name := range columnNames {
+		if cv, ok := row.Values[name]; ok {
+			keys[i] = fmt.Sprintf("%v", cv.Value)
+		}
+	}
+	return strings.Join(keys, "\x00")
+}
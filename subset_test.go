@@ -0,0 +1,39 @@
+package mysql
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ColumnTuples(t *testing.T) {
+	userID := driver.NewColumn("user_id", 0, driver.ColumnTypeInt, false, false)
+	rows := []*driver.Row{
+		&driver.Row{Values: driver.RowValues{"user_id": driver.NewGenericColumnValue(userID, sql.NullInt64{Int64: 1, Valid: true})}},
+		&driver.Row{Values: driver.RowValues{"user_id": driver.NewGenericColumnValue(userID, sql.NullInt64{})}},
+		&driver.Row{Values: driver.RowValues{"user_id": driver.NewGenericColumnValue(userID, sql.NullInt64{Int64: 2, Valid: true})}},
+		&driver.Row{Values: driver.RowValues{"user_id": driver.NewGenericColumnValue(userID, sql.NullInt64{Int64: 1, Valid: true})}},
+	}
+
+	tuples, err := columnTuples(rows, []string{"user_id"})
+	if assert.NoError(t, err) {
+		assert.Equal(t, [][]interface{}{{int64(1)}, {int64(2)}}, tuples)
+	}
+
+	_, err = columnTuples(rows, []string{"order_id"})
+	assert.EqualError(t, err, "column not found: order_id")
+}
+
+func Test_GenerateInCondition(t *testing.T) {
+	q, err := generateInCondition([]string{"id"}, 3)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "`id` IN (?, ?, ?)", q)
+	}
+
+	q, err = generateInCondition([]string{"tenant_id", "id"}, 2)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "(`tenant_id`, `id`) IN ((?, ?), (?, ?))", q)
+	}
+}
@@ -0,0 +1,130 @@
+package mysql
+
+import (
+	"context"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	sqldriver "database/sql/driver"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	gomysql "github.com/go-sql-driver/mysql"
+)
+
+// DSN parameters for TLS with files. They register a tls.Config with go-sql-driver,
+// so they replace the tls parameter.
+const (
+	paramTLSCA         = "tlsCA"
+	paramTLSCert       = "tlsCert"
+	paramTLSKey        = "tlsKey"
+	paramTLSServerName = "tlsServerName"
+	// paramServerPubKeyFile names a PEM file with the RSA public key of the server, which
+	// caching_sha2_password and sha256_password need to send passwords without TLS.
+	paramServerPubKeyFile = "serverPubKeyFile"
+)
+
+// PasswordProvider returns the password of a new connection, like a short lived IAM token.
+// Tokens are usually sent as cleartext, which needs allowCleartextPasswords=true and should go over TLS.
+type PasswordProvider func(ctx context.Context) (string, error)
+
+func (opts *Options) hasTLSFiles() bool {
+	return opts.TLSCAFile != "" || opts.TLSCertFile != "" || opts.TLSKeyFile != ""
+}
+
+// tlsConfig builds the tls.Config of the TLS files of opts.
+func (opts *Options) tlsConfig() (*tls.Config, error) {
+	cfg := &tls.Config{ServerName: opts.TLSServerName}
+	if opts.TLSCAFile != "" {
+		data, err := ioutil.ReadFile(opts.TLSCAFile)
+		if err != nil {
+			return nil, err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("no certificate found in %s", opts.TLSCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+	if opts.TLSCertFile != "" || opts.TLSKeyFile != "" {
+		if opts.TLSCertFile == "" || opts.TLSKeyFile == "" {
+			return nil, errors.New("client certificate and key must be set together")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.TLSCertFile, opts.TLSKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+	return cfg, nil
+}
+
+// registerTLSConfig registers the TLS files of opts with go-sql-driver and refers to them in cfg.
+// The name depends on the files only, so opening the same files again reuses it.
+func (opts *Options) registerTLSConfig(cfg *gomysql.Config) (*tls.Config, error) {
+	tlsCfg, err := opts.tlsConfig()
+	if err != nil {
+		return nil, err
+	}
+	name := "tamate-" + registryKey(opts.TLSCAFile, opts.TLSCertFile, opts.TLSKeyFile, opts.TLSServerName)
+	if err := gomysql.RegisterTLSConfig(name, tlsCfg); err != nil {
+		return nil, err
+	}
+	cfg.TLSConfig = name
+	return tlsCfg, nil
+}
+
+// registerServerPubKey registers the server public key file of opts with go-sql-driver and refers to it in cfg.
+func (opts *Options) registerServerPubKey(cfg *gomysql.Config) error {
+	data, err := ioutil.ReadFile(opts.ServerPubKeyFile)
+	if err != nil {
+		return err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return fmt.Errorf("no public key found in %s", opts.ServerPubKeyFile)
+	}
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return err
+	}
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return fmt.Errorf("public key in %s is not RSA", opts.ServerPubKeyFile)
+	}
+	name := "tamate-" + registryKey(opts.ServerPubKeyFile)
+	gomysql.RegisterServerPubKey(name, rsaPub)
+	cfg.ServerPubKey = name
+	return nil
+}
+
+func registryKey(parts ...string) string {
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x00")))
+	return hex.EncodeToString(sum[:8])
+}
+
+// passwordConnector opens connections of dsn with a password from provider, fetched for every connection.
+type passwordConnector struct {
+	dsn      string
+	provider PasswordProvider
+}
+
+func (pc *passwordConnector) Connect(ctx context.Context) (sqldriver.Conn, error) {
+	cfg, err := gomysql.ParseDSN(pc.dsn)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Passwd, err = pc.provider(ctx); err != nil {
+		return nil, err
+	}
+	return gomysql.MySQLDriver{}.Open(cfg.FormatDSN())
+}
+
+func (pc *passwordConnector) Driver() sqldriver.Driver {
+	return gomysql.MySQLDriver{}
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	gomysql "github.com/go-sql-driver/mysql"
+	"github.com/stretchr/testify/assert"
+)
+
+// writeTestCertificates writes a CA, a client certificate signed by it and their keys to dir.
+func writeTestCertificates(t *testing.T, dir string) (caFile, certFile, keyFile, pubKeyFile string) {
+	caKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	caTmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "tamate test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTmpl, caTmpl, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	clientKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	clientTmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "tamate"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	clientDER, err := x509.CreateCertificate(rand.Reader, clientTmpl, caTmpl, &clientKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubDER, err := x509.MarshalPKIXPublicKey(&caKey.PublicKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	write := func(name, typ string, der []byte) string {
+		path := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	return write("ca.pem", "CERTIFICATE", caDER),
+		write("client-cert.pem", "CERTIFICATE", clientDER),
+		write("client-key.pem", "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(clientKey)),
+		write("server-pub.pem", "PUBLIC KEY", pubDER)
+}
+
+func Test_ConfigureDSNWithTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tamate-tls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	caFile, certFile, keyFile, pubKeyFile := writeTestCertificates(t, dir)
+
+	dsn, opts, err := parseDSNOptions("root:pass@tcp(db.example.com:3306)/tamate?tlsCA=" + url.QueryEscape(caFile) + "&tlsCert=" + url.QueryEscape(certFile) + "&tlsKey=" + url.QueryEscape(keyFile) + "&serverPubKeyFile=" + url.QueryEscape(pubKeyFile))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, caFile, opts.TLSCAFile)
+	assert.Equal(t, pubKeyFile, opts.ServerPubKeyFile)
+
+	dsn, tlsCfg, err := opts.configureDSN(dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	if assert.NotNil(t, tlsCfg) {
+		assert.NotNil(t, tlsCfg.RootCAs)
+		assert.Len(t, tlsCfg.Certificates, 1)
+	}
+	// go-sql-driver only parses names it has registered
+	cfg, err := gomysql.ParseDSN(dsn)
+	if assert.NoError(t, err) {
+		assert.NotEmpty(t, cfg.TLSConfig)
+		assert.NotEmpty(t, cfg.ServerPubKey)
+		assert.Empty(t, cfg.Params)
+	}
+
+	_, _, err = (&Options{TLSCertFile: certFile}).configureDSN(dsn)
+	assert.Error(t, err)
+	_, _, err = (&Options{TLSCAFile: keyFile}).configureDSN(dsn)
+	assert.Error(t, err)
+}
@@ -0,0 +1,191 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/go-tamate/tamate/driver"
+)
+
+// View is a view of the connected database.
+type View struct {
+	Name string
+	// Definition is the SELECT of the view, with names of the connected database unqualified.
+	Definition string
+	// CheckOption is NONE, CASCADED or LOCAL.
+	CheckOption string
+	// SecurityType is DEFINER or INVOKER.
+	SecurityType string
+	// Updatable views take writes to the tables they select from.
+	Updatable bool
+}
+
+// ViewNotUpdatableError refuses writes to a view which cannot be updated.
+type ViewNotUpdatableError struct {
+	ViewName string
+}
+
+func (e *ViewNotUpdatableError) Error() string {
+	return fmt.Sprintf("view %s is not updatable", e.ViewName)
+}
+
+// GetViews reads the views of the connected database. Their definitions need the SHOW VIEW privilege.
+func (c *Conn) GetViews(ctx context.Context) ([]*View, error) {
+	var result []*View
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		var err error
+		result, err = c.getViews(ctx, c.reader(ctx), "")
+		return err
+	})
+	return result, err
+}
+
+// GetView reads a view, or returns nil when there is no view of that name.
+func (c *Conn) GetView(ctx context.Context, viewName string) (*View, error) {
+	var result *View
+	err := c.retryRead(ctx, func(ctx context.Context) error {
+		views, err := c.getViews(ctx, c.reader(ctx), viewName)
+		if err != nil {
+			return err
+		}
+		if len(views) > 0 {
+			result = views[0]
+		}
+		return nil
+	})
+	return result, err
+}
+
+// getViews reads the views, or the one of viewName unless it is empty.
+func (c *Conn) getViews(ctx context.Context, db queryer, viewName string) ([]*View, error) {
+	database, err := getDatabaseNameDB(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := getViewsDB(ctx, db, viewName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var views []*View
+	for rows.Next() {
+		v := &View{}
+		var updatable string
+		if err := rows.Scan(&v.Name, &v.Definition, &v.CheckOption, &v.SecurityType, &updatable); err != nil {
+			return nil, err
+		}
+		// the server qualifies every name, which would tie the view to this database
+		v.Definition = strings.Replace(v.Definition, "`"+database+"`.", "", -1)
+		v.Updatable = updatable == "YES"
+		views = append(views, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return views, nil
+}
+
+// sortViews sorts views so that every view comes after the views it selects from.
+func sortViews(views []*View) []*View {
+	byName := make(map[string]*View, len(views))
+	names := make([]string, len(views))
+	for i, v := range views {
+		byName[v.Name] = v
+		names[i] = v.Name
+	}
+	// references between views order them like foreign keys order tables
+	var refs []*ForeignKey
+	for _, v := range views {
+		for _, other := range views {
+			if other != v && strings.Contains(v.Definition, "`"+other.Name+"`") {
+				refs = append(refs, &ForeignKey{TableName: v.Name, ReferencedTableName: other.Name})
+			}
+		}
+	}
+	plan := planLoad(names, refs)
+	sorted := make([]*View, len(plan.LoadOrder))
+	for i, name := range plan.LoadOrder {
+		sorted[i] = byName[name]
+	}
+	return sorted
+}
+
+// SetViews creates or replaces views in dependency order. The tables they select from have to exist.
+func (c *Conn) SetViews(ctx context.Context, views []*View) error {
+	if err := c.checkWrite("SetViews"); err != nil {
+		return err
+	}
+	names := make([]string, len(views))
+	for i, v := range views {
+		names[i] = v.Name
+	}
+	return c.withWriteLock(ctx, names, func(ctx context.Context) error {
+		return c.setViews(ctx, views)
+	})
+}
+
+func (c *Conn) setViews(ctx context.Context, views []*View) error {
+	for _, v := range sortViews(views) {
+		if err := createViewDB(ctx, c.db, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replaceViewRows replaces the rows of an updatable view, which cannot be recreated like a table.
+func (c *Conn) replaceViewRows(ctx context.Context, viewName string, rows []*driver.Row) error {
+	views, err := c.getViews(ctx, c.db, viewName)
+	if err != nil {
+		return err
+	}
+	if len(views) == 0 {
+		return errors.New("view not found: " + viewName)
+	}
+	if !views[0].Updatable {
+		return &ViewNotUpdatableError{ViewName: viewName}
+	}
+
+	// rows go through the view to its tables, so they are replaced in one transaction
+	return c.retry(ctx, func(ctx context.Context) error {
+		tx, err := c.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		if err := replaceRowsTx(ctx, tx, viewName, rows); err != nil {
+			if rerr := tx.Rollback(); rerr != nil {
+				return fmt.Errorf("%v (rollback: %v)", err, rerr)
+			}
+			return err
+		}
+		return tx.Commit()
+	})
+}
+
+func replaceRowsTx(ctx context.Context, tx *sql.Tx, tableName string, rows []*driver.Row) error {
+	if err := deleteRowsDB(ctx, tx, tableName); err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if _, err := insertRowDB(ctx, tx, tableName, row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkNotView refuses operation on a view, which is not a table.
+func (c *Conn) checkNotView(ctx context.Context, operation, tableName string) error {
+	tableType, err := getTableTypeDB(ctx, c.db, tableName)
+	if err != nil {
+		return err
+	}
+	if tableType == tableTypeView {
+		return errors.New(operation + " cannot change view " + tableName + ", which SetViews replaces")
+	}
+	return nil
+}
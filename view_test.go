@@ -0,0 +1,92 @@
+package mysql
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/go-tamate/tamate/driver"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ViewQueries(t *testing.T) {
+	v := &View{
+		Name:         "adult",
+		Definition:   "select `user`.`id` AS `id` from `user` where (`user`.`age` >= 20)",
+		CheckOption:  "CASCADED",
+		SecurityType: "INVOKER",
+	}
+	q, err := generateCreateViewQuery(v)
+	if assert.NoError(t, err) {
+		assert.Equal(t, "CREATE OR REPLACE SQL SECURITY INVOKER VIEW `adult` AS select `user`.`id` AS `id` from `user` where (`user`.`age` >= 20) WITH CASCADED CHECK OPTION", q)
+	}
+	_, err = generateCreateViewQuery(&View{Name: "empty"})
+	assert.Error(t, err)
+
+	senior := &View{Name: "senior", Definition: "select `adult`.`id` AS `id` from `adult`"}
+	alpha := &View{Name: "alpha", Definition: "select `senior`.`id` AS `id` from `senior`"}
+	other := &View{Name: "other", Definition: "select 1 AS `one`"}
+	assert.Equal(t, []*View{v, other, senior, alpha}, sortViews([]*View{alpha, senior, other, v}))
+}
+
+func Test_Views(t *testing.T) {
+	var (
+		ctx       = context.Background()
+		dbName    = "tamatest"
+		tableName = "example"
+		dsn       = fmt.Sprintf("%s:%s@/%s", ConnectionTestUser, ConnectionTestPassword, dbName)
+	)
+
+	// Prepare test
+	fakeSchema := &driver.Schema{
+		Name: tableName,
+		PrimaryKey: &driver.Key{
+			KeyType:     driver.KeyTypePrimary,
+			ColumnNames: []string{"id"},
+		},
+		Columns: []*driver.Column{
+			driver.NewColumn("id", 0, driver.ColumnTypeInt, true, false),
+			driver.NewColumn("name", 1, driver.ColumnTypeString, true, false),
+		},
+	}
+	assert.NoError(t, dropDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createDatabase(ConnectionTestUser, ConnectionTestPassword, dbName))
+	assert.NoError(t, createTable(ConnectionTestUser, ConnectionTestPassword, dbName, fakeSchema))
+
+	// Open connection
+	conn, err := (&mysqlDriver{}).Open(ctx, dsn)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer conn.Close()
+	c := conn.(*Conn)
+
+	// Creating views in dependency order
+	names := &View{Name: "names", Definition: "select `example`.`id` AS `id`, `example`.`name` AS `name` from `example`"}
+	count := &View{Name: "name_count", Definition: "select count(0) AS `count` from `names`"}
+	assert.NoError(t, c.SetViews(ctx, []*View{count, names}))
+
+	ds, err := c.GetDatabaseSchema(ctx)
+	if assert.NoError(t, err) && assert.Len(t, ds.Views, 2) {
+		assert.Equal(t, "name_count", ds.Views[0].Name)
+		assert.False(t, ds.Views[0].Updatable)
+		assert.Equal(t, "names", ds.Views[1].Name)
+		assert.True(t, ds.Views[1].Updatable)
+		assert.NotContains(t, ds.Views[1].Definition, dbName)
+	}
+
+	// Writing through views
+	row := &driver.Row{
+		Values: map[string]*driver.GenericColumnValue{
+			"id":   driver.NewGenericColumnValue(fakeSchema.Columns[0], 1),
+			"name": driver.NewGenericColumnValue(fakeSchema.Columns[1], "tamate"),
+		},
+	}
+	assert.NoError(t, c.SetRows(ctx, "names", []*driver.Row{row}))
+	rows, err := c.GetRows(ctx, tableName)
+	if assert.NoError(t, err) {
+		assert.Len(t, rows, 1)
+	}
+	assert.IsType(t, &ViewNotUpdatableError{}, c.SetRows(ctx, "name_count", nil))
+	assert.Error(t, c.SetSchema(ctx, "names", fakeSchema))
+}